@@ -0,0 +1,792 @@
+package accord
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	isoDateLayout string = "2006-01-02"
+)
+
+//  Debt is a debt placed with us and, once a portfolio has been computed, everything worked out about it
+type Debt struct {
+	ID                     int                `json:"id"`
+	Amount                 decimal.Decimal    `json:"amount"`
+	Currency               string             `json:"currency,omitempty"` //  ISO 4217 code; USD when blank
+	InPaymentPlan          bool               `json:"is_in_payment_plan"`
+	RemainingAmount        decimal.Decimal    `json:"remaining_amount"` //  Never negative; anything paid beyond what was owed is in CreditBalance
+	CreditBalance          decimal.Decimal    `json:"credit_balance"`   //  What was overpaid and is due back to the debtor
+	NextPaymentDate        *string            `json:"next_payment_due_date"`
+	ProjectedPayoffDate    *string            `json:"projected_payoff_date"`
+	RemainingInstallments  *int               `json:"remaining_installments"`
+	FinalInstallmentAmount *decimal.Decimal   `json:"final_installment_amount"`
+	Delinquency            *Delinquency       `json:"delinquency,omitempty"`
+	PlanStatus             string             `json:"plan_status,omitempty"`
+	PlanStatusHistory      []StatusTransition `json:"plan_status_history,omitempty"`
+	InterestTerms          *InterestTerms     `json:"interest_terms,omitempty"` //  Optional; debts without terms don't accrue interest
+	Interest               *InterestBreakdown `json:"interest,omitempty"`
+	Fees                   *FeeBreakdown      `json:"fees,omitempty"`
+	SettlementDiscount     *decimal.Decimal   `json:"settlement_discount,omitempty"` //  Amount less the plan's amount_to_pay, for plans that settle for less
+	SettlementPercent      *decimal.Decimal   `json:"settlement_percent,omitempty"`  //  amount_to_pay as a percentage of Amount
+	Forgiven               *ForgivenAmount    `json:"forgiven,omitempty"`            //  Set once a settled plan completes
+	ZeroAmountPolicy       string             `json:"zero_amount_policy,omitempty"`  //  How a plan's amount_to_pay of zero was read
+	paymentPlan            *PaymentPlan
+	asOf                   CivilDate //  The date evaluate worked the debt out as of
+	settings               *settings //  What the debt is worked out with; defaultSettings when nil
+}
+
+//  PaymentPlan is how a debtor agreed to pay a debt off
+type PaymentPlan struct {
+	ID                   int             `json:"id"`
+	DebtID               int             `json:"debt_id"`
+	AmountToPay          decimal.Decimal `json:"amount_to_pay"`
+	Currency             string          `json:"currency,omitempty"` //  Has to match the debt's currency
+	InstallmentFrequency string          `json:"installment_frequency"`
+	InstallmentAmount    decimal.Decimal `json:"installment_amount"`
+	StartDate            string          `json:"start_date"`
+	MatchPolicy          string          `json:"payment_match_policy,omitempty"` //  Optional per-plan override of the run's match policy
+	FeeSchedule          *FeeSchedule    `json:"fee_schedule,omitempty"`         //  Optional; plans without one don't charge fees
+	startDate            CivilDate       //  The date converted to a civil date
+	debtAmount           decimal.Decimal //  The amount of the debt the plan is for
+	matchPolicy          *MatchPolicy    //  MatchPolicy parsed, nil when the plan uses the run's policy
+	payments             []Payment
+	transactions         []Payment //  Every payment record for the plan up to the as-of date, whether it counts or not
+	returnedPayments     []Payment //  Payments that were returned, which count for nothing but fees
+	schedule             Schedule  //  Installments in due date order
+	settings             *settings //  What the plan is worked out with; defaultSettings when nil
+}
+
+//  Payment is a payment record for a plan: money received, or a reversal, chargeback or refund of it
+type Payment struct {
+	Amount            decimal.Decimal `json:"amount"`
+	Currency          string          `json:"currency,omitempty"` //  Has to match the plan's currency
+	Date              string          `json:"date"`
+	date              CivilDate       //  The date converted to a civil date in the business time zone
+	PaymentPlanID     int             `json:"payment_plan_id"`
+	ID                int             `json:"id,omitempty"`
+	Type              string          `json:"type,omitempty"`                //  payment, reversal, chargeback or refund; payment when blank
+	Status            string          `json:"status,omitempty"`              //  settled, pending, failed, reversed or refunded; settled when blank
+	OriginalPaymentID *int            `json:"original_payment_id,omitempty"` //  The payment a reversal, chargeback or refund is for
+}
+
+//  NewDebt makes a debt, checking the amount and currency
+func NewDebt(id int, amount decimal.Decimal, currency string) (Debt, error) {
+	rvalue := Debt{ID: id, Amount: amount, Currency: currency}
+
+	if amount.IsNegative() {
+		return rvalue, fmt.Errorf("Debt %v has a negative amount %v", id, amount)
+	}
+	return rvalue, rvalue.Normalize()
+}
+
+//  NewPaymentPlan makes a plan for a debt, checking it can be scheduled
+func NewPaymentPlan(id int, debtID int, amountToPay decimal.Decimal, frequency string, installmentAmount decimal.Decimal, startDate string) (PaymentPlan, error) {
+	rvalue := PaymentPlan{ID: id, DebtID: debtID, AmountToPay: amountToPay, InstallmentFrequency: frequency, InstallmentAmount: installmentAmount, StartDate: startDate}
+
+	if amountToPay.IsNegative() || !installmentAmount.IsPositive() {
+		return rvalue, fmt.Errorf("Payment plan %v needs an amount_to_pay of zero or more and a positive installment_amount", id)
+	}
+	if _, err := parseRecurrence(frequency); err != nil {
+		return rvalue, fmt.Errorf("Payment plan %v:%v", id, err)
+	}
+	return rvalue, rvalue.Normalize()
+}
+
+//  NewPayment makes a payment of amount towards a plan. Its date is read in the business time zone
+//  of the portfolio it's computed in
+func NewPayment(id int, planID int, amount decimal.Decimal, date string) (Payment, error) {
+	rvalue := Payment{ID: id, PaymentPlanID: planID, Amount: amount, Date: date}
+
+	if !amount.IsPositive() {
+		return rvalue, fmt.Errorf("Payment %v has to be for a positive amount, not %v", id, amount)
+	}
+	return rvalue, rvalue.Normalize(time.UTC)
+}
+
+//  Normalize fills in the debt's defaults and checks it, for debts read from elsewhere
+func (debt *Debt) Normalize() error {
+	var err error = nil
+
+	debt.Currency, err = normalizeCurrency(debt.Currency)
+
+	if err != nil {
+		return fmt.Errorf("Debt %v:%v", debt.ID, err)
+	}
+
+	if debt.InterestTerms != nil {
+		//  Validating tidies the terms up, so work on a copy rather than whatever the caller passed in
+		terms := *debt.InterestTerms
+		if err = terms.validate(); err != nil {
+			return fmt.Errorf("Debt %v:%v", debt.ID, err)
+		}
+		debt.InterestTerms = &terms
+	}
+	return nil
+}
+
+//  Normalize parses the plan's start date, currency and match policy and checks any fees it charges,
+//  for plans read from elsewhere
+func (plan *PaymentPlan) Normalize() error {
+	var err error = nil
+
+	//  Every due date counts from the start date, so a plan can't do without one
+	if len(plan.StartDate) == 0 {
+		return fmt.Errorf("Payment plan %v has no start_date", plan.ID)
+	}
+	plan.startDate, err = ParseCivilDate(plan.StartDate)
+
+	if err != nil {
+		return err
+	}
+
+	plan.Currency, err = normalizeCurrency(plan.Currency)
+
+	if err != nil {
+		return fmt.Errorf("Payment plan %v:%v", plan.ID, err)
+	}
+
+	plan.matchPolicy = nil
+	if len(plan.MatchPolicy) > 0 {
+		policy, tempErr := parseMatchPolicy(plan.MatchPolicy)
+
+		if tempErr != nil {
+			return fmt.Errorf("Payment plan %v:%v", plan.ID, tempErr)
+		}
+		plan.matchPolicy = &policy
+	}
+
+	if plan.FeeSchedule != nil {
+		if err = plan.FeeSchedule.validate(); err != nil {
+			return fmt.Errorf("Payment plan %v:%v", plan.ID, err)
+		}
+	}
+	return nil
+}
+
+//  Normalize fills in the payment's defaults and checks it, for payments read from elsewhere.
+//  A payment dated with a timestamp falls on the calendar day it was in loc
+func (pmt *Payment) Normalize(loc *time.Location) error {
+	var err error = nil
+
+	pmt.Currency, err = normalizeCurrency(pmt.Currency)
+
+	if err != nil {
+		return err
+	}
+
+	err = pmt.normalizePaymentStatus()
+
+	if err != nil {
+		return err
+	}
+
+	if len(pmt.Date) > 0 {
+		pmt.date, err = parseBusinessDate(pmt.Date, loc)
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//  PaymentPlan is a copy of the debt's payment plan, or nil when it doesn't have one
+func (debt *Debt) PaymentPlan() *PaymentPlan {
+	if debt.paymentPlan == nil {
+		return nil
+	}
+	plan := debt.paymentPlan.clone()
+	return &plan
+}
+
+//  Schedule is a copy of the plan's installments as of the date its debt was evaluated
+func (plan *PaymentPlan) Schedule() Schedule {
+	rvalue := append(Schedule(nil), plan.schedule...)
+
+	for idx := range rvalue {
+		if rvalue[idx].PaidOn != nil {
+			paidOn := *rvalue[idx].PaidOn
+			rvalue[idx].PaidOn = &paidOn
+		}
+	}
+	return rvalue
+}
+
+//  Payments is a copy of the plan's payments that count towards its debt
+func (plan *PaymentPlan) Payments() []Payment {
+	rvalue := make([]Payment, 0, len(plan.payments))
+
+	for _, pmt := range plan.payments {
+		rvalue = append(rvalue, pmt.clone())
+	}
+	return rvalue
+}
+
+//  clone is a copy of the debt that shares nothing a caller could change with it. The plan itself is
+//  shared since it can only be reached through PaymentPlan, which copies it
+func (debt *Debt) clone() Debt {
+	rvalue := *debt
+
+	if debt.NextPaymentDate != nil {
+		nextPaymentDate := *debt.NextPaymentDate
+		rvalue.NextPaymentDate = &nextPaymentDate
+	}
+	if debt.ProjectedPayoffDate != nil {
+		projectedPayoffDate := *debt.ProjectedPayoffDate
+		rvalue.ProjectedPayoffDate = &projectedPayoffDate
+	}
+	if debt.RemainingInstallments != nil {
+		remainingInstallments := *debt.RemainingInstallments
+		rvalue.RemainingInstallments = &remainingInstallments
+	}
+	if debt.FinalInstallmentAmount != nil {
+		finalInstallmentAmount := *debt.FinalInstallmentAmount
+		rvalue.FinalInstallmentAmount = &finalInstallmentAmount
+	}
+	if debt.Delinquency != nil {
+		delinquency := *debt.Delinquency
+		rvalue.Delinquency = &delinquency
+	}
+	if debt.InterestTerms != nil {
+		terms := *debt.InterestTerms
+		rvalue.InterestTerms = &terms
+	}
+	if debt.Interest != nil {
+		interest := *debt.Interest
+		rvalue.Interest = &interest
+	}
+	if debt.SettlementDiscount != nil {
+		discount := *debt.SettlementDiscount
+		rvalue.SettlementDiscount = &discount
+	}
+	if debt.SettlementPercent != nil {
+		percent := *debt.SettlementPercent
+		rvalue.SettlementPercent = &percent
+	}
+	if debt.Forgiven != nil {
+		forgiven := *debt.Forgiven
+		rvalue.Forgiven = &forgiven
+	}
+	if debt.Fees != nil {
+		fees := *debt.Fees
+		if fees.Assessments != nil {
+			fees.Assessments = append([]FeeAssessment(nil), fees.Assessments...)
+		}
+		rvalue.Fees = &fees
+	}
+	if debt.PlanStatusHistory != nil {
+		rvalue.PlanStatusHistory = append([]StatusTransition(nil), debt.PlanStatusHistory...)
+	}
+	return rvalue
+}
+
+//  clone is a copy of the plan that shares nothing a caller could change with it. Its payments and
+//  schedule are shared since they can only be read through Payments and Schedule, which copy them
+func (plan *PaymentPlan) clone() PaymentPlan {
+	rvalue := *plan
+
+	if plan.FeeSchedule != nil {
+		fees := *plan.FeeSchedule
+		rvalue.FeeSchedule = &fees
+	}
+	return rvalue
+}
+
+//  clone is a copy of the payment that shares nothing a caller could change with it
+func (pmt *Payment) clone() Payment {
+	rvalue := *pmt
+
+	if pmt.OriginalPaymentID != nil {
+		originalID := *pmt.OriginalPaymentID
+		rvalue.OriginalPaymentID = &originalID
+	}
+	return rvalue
+}
+
+//  BusinessDate is the calendar day the payment was made on in the business time zone
+func (pmt *Payment) BusinessDate() CivilDate {
+	return pmt.date
+}
+
+//  normalizeData takes the disparate objects returned by the various web-service calls and place them
+//  into a nice neat hierarchy, matching paymentPlans to debts and putting payments under payment plans.
+//  Everything is evaluated as of config's evaluation date: payments dated after it haven't happened yet
+//  as far as remaining amount, plan activity and next due date are concerned. It works on copies, so
+//  none of its arguments change, and returns the debts it worked out.
+//  The payments are grouped by plan in a single pass, then the debts are worked out by config's
+//  workers at the same time, so the cost grows with the number of debts plus payments
+func normalizeData(debts map[int]Debt, paymentPlans map[int]PaymentPlan, payments []Payment, config *settings) (map[int]Debt, error) {
+	var err error = nil
+
+	asOf := config.evaluationDate()
+
+	paymentsByPlan := groupPaymentsByPlan(payments, asOf)
+
+	//  Going through the debts in id order means a bad input always gives the same error
+	debtIds := sortedDebtIDs(debts)
+	joined := make([]Debt, len(debtIds))
+	matchedPlans := 0
+
+	for idx, debtId := range debtIds {
+		debt := debts[debtId]
+		debt.settings = config
+		debt.asOf = asOf
+
+		//  Does this debt have an associated payment plan?
+		plan, ok := paymentPlans[debtId]
+
+		if ok {
+			plan.settings = config
+
+			//  Amounts in different currencies can't be added up, so the plan has to be in the debt's currency
+			if currencyOrDefault(plan.Currency) != debt.currency() {
+				return nil, fmt.Errorf("Payment plan %v is in %v but debt %v is in %v", plan.ID, currencyOrDefault(plan.Currency), debtId, debt.currency())
+			}
+
+			if err = plan.validateAmountToPay(); err != nil {
+				return nil, err
+			}
+
+			debt.paymentPlan = &plan
+			debt.paymentPlan.debtAmount = debt.Amount
+
+			//  Count it off so we can detect orphans at the end
+			matchedPlans++
+
+			//  Now attach the payments for that particular payment plan, which are already
+			//  limited to the ones on or before the as-of date
+			planId := debt.paymentPlan.ID
+			for _, pmt := range paymentsByPlan[planId] {
+				//  ...and so do its payments
+				if currencyOrDefault(pmt.Currency) != debt.currency() {
+					return nil, fmt.Errorf("Payment of %v on %v for plan %v isn't in %v", pmt.Amount, pmt.Date, planId, debt.currency())
+				}
+			}
+			debt.paymentPlan.transactions = paymentsByPlan[planId]
+		} // end if ok
+		joined[idx] = debt
+	} //  end outer debt loop
+
+	//  Work out everything else from those
+	err = evaluateDebts(joined, asOf, config.workers)
+	if err != nil {
+		return nil, err
+	}
+
+	//  Store the worked out debts in the results
+	rvalue := make(map[int]Debt, len(joined))
+	for idx, debtId := range debtIds {
+		rvalue[debtId] = joined[idx]
+	}
+
+	//  If we have any plans leftover, that's an error
+	if len(paymentPlans)-matchedPlans > 0 {
+		//  in a production system these would show up in an exception report.
+		err = fmt.Errorf("Found orphaned payment plans")
+	}
+
+	return rvalue, err
+}
+
+//  groupPaymentsByPlan puts the payments dated on or before asOf under their payment_plan_id, each plan's
+//  in date order. The groups share one backing array sized up front, rather than growing a slice per plan
+func groupPaymentsByPlan(payments []Payment, asOf CivilDate) map[int][]Payment {
+	counts := make(map[int]int)
+	total := 0
+	for idx := range payments {
+		if !payments[idx].date.After(asOf) {
+			counts[payments[idx].PaymentPlanID]++
+			total++
+		}
+	}
+
+	backing := make([]Payment, total)
+	rvalue := make(map[int][]Payment, len(counts))
+	offset := 0
+	for planId, count := range counts {
+		//  Capped at the group's size, so appending to one group can never run into the next
+		rvalue[planId] = backing[offset:offset:(offset + count)]
+		offset += count
+	}
+
+	for idx := range payments {
+		if !payments[idx].date.After(asOf) {
+			planId := payments[idx].PaymentPlanID
+			rvalue[planId] = append(rvalue[planId], payments[idx])
+		}
+	}
+
+	//  Payments usually arrive in date order already, so only sort the groups that need it
+	for _, group := range rvalue {
+		if !sort.SliceIsSorted(group, func(i, j int) bool { return group[i].date.Before(group[j].date) }) {
+			sort.SliceStable(group, func(i, j int) bool { return group[i].date.Before(group[j].date) })
+		}
+	}
+	return rvalue
+}
+
+//  evaluateDebts works each debt out as of asOf, with up to workers of them being worked out at once.
+//  Every debt has its own plan and nothing else they share is written to, so they don't need locking.
+//  When debts fail, the error is the first of them's, so the same inputs always give the same error
+func evaluateDebts(debts []Debt, asOf CivilDate, workers int) error {
+	const batchSize int = 256
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	batches := make(chan int, workers)
+	errs := make([]error, len(debts))
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range batches {
+				end := start + batchSize
+				if end > len(debts) {
+					end = len(debts)
+				}
+				for idx := start; idx < end; idx++ {
+					errs[idx] = debts[idx].evaluate(asOf)
+				}
+			}
+		}()
+	}
+
+	for start := 0; start < len(debts); start += batchSize {
+		batches <- start
+	}
+	close(batches)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//  currentSettings are the settings the debt is worked out with
+func (debt *Debt) currentSettings() *settings {
+	if debt.settings != nil {
+		return debt.settings
+	}
+	return defaultSettings
+}
+
+//  currentSettings are the settings the plan is worked out with
+func (plan *PaymentPlan) currentSettings() *settings {
+	if plan.settings != nil {
+		return plan.settings
+	}
+	return defaultSettings
+}
+
+//  evaluate works out the state of a debt with a plan as of a date from the plan and its payment
+//  transactions, which have to be dated on or before asOf
+func (debt *Debt) evaluate(asOf CivilDate) error {
+	if debt.paymentPlan == nil {
+		return nil
+	}
+
+	debt.asOf = asOf
+
+	//  Say how we read an amount_to_pay of zero, since it changes everything else
+	if debt.paymentPlan.AmountToPay.IsZero() {
+		debt.ZeroAmountPolicy = debt.paymentPlan.currentSettings().zeroAmountPolicy
+	}
+
+	//  Store the payments that count in the plan. Returned payments are kept
+	//  aside since they only matter for fees
+	debt.paymentPlan.payments, debt.paymentPlan.returnedPayments = settlePayments(debt.paymentPlan.transactions)
+
+	//  Generate a payment schedule based on the parameters,
+	//  which would probably be needed by a UI somewhere anyway
+	debt.paymentPlan.generatePaymentSchedule()
+
+	//  Replay the plan once. How what's been paid splits between fees, interest and principal,
+	//  what's still owed and the plan's status all come from the same balances
+	balances := debt.calculateBalances(asOf)
+
+	//  Apply what went to principal to the installments it covers
+	debt.paymentPlan.allocatePayments(balances)
+	debt.interestFrom(balances, true)
+	debt.feesFrom(balances, true)
+
+	//  ...and what's still owed, or owed back to them
+	remaining, err := debt.remainingAmountFrom(balances, true)
+	if err != nil {
+		return fmt.Errorf("Debt %v:%v", debt.ID, err)
+	}
+
+	//  Get the next payment date based on the payments that have
+	//  been made
+	if remaining.IsPositive() {
+		debt.nextPaymentDateFrom(remaining, true)
+
+		//  ...and when they'll be done if they keep to the plan
+		debt.projectPayoffFrom(remaining, true)
+	}
+
+	debt.InPaymentPlan = debt.planActiveFrom(remaining)
+
+	//  Work out whether they've fallen behind
+	debt.calculateDelinquency(asOf, true)
+
+	//  ...and where that leaves the plan
+	debt.planStatusFrom(asOf, balances, true)
+
+	//  ...and, for a plan settling for less, what's been forgiven
+	debt.calculateSettlement(true)
+	return nil
+}
+
+//  sumTotalPayments adds all payments that have been made to a debt
+func (debt *Debt) sumTotalPayments() (Money, int, error) {
+	var err error = nil
+	var paymentCount int
+
+	rvalue := newMoney(decimal.Zero, debt.Currency)
+
+	if debt.paymentPlan != nil {
+
+		plan := debt.paymentPlan
+
+		if plan.payments != nil {
+			for _, payment := range plan.payments {
+				paymentCount++
+				rvalue, err = rvalue.add(newMoney(payment.Amount, payment.Currency))
+				if err != nil {
+					return rvalue, paymentCount, err
+				}
+				rvalue = rvalue.round(plan.currentSettings().rounding)
+			}
+		}
+	}
+
+	return rvalue, paymentCount, err
+}
+
+//  isDebtPaidOff checks if a debt is paid or not
+func (debt *Debt) isDebtPaidOff() bool {
+	//  Worked out afresh every time, so it doesn't matter what's been calculated before.
+	//  The remaining amount never goes below zero; anything over-paid is credit
+	remaining, _ := debt.calculateRemainingAmount(false)
+	return !remaining.IsPositive()
+}
+
+//  amountToPay is what the debtor owes before any payments
+func (debt *Debt) amountToPay() decimal.Decimal {
+	//  Start by the setting to the debt's amount
+	rvalue := debt.Amount
+
+	//  If there's a payment plan, use the amount_to_pay from there. A zero is up to the zero amount policy
+	if debt.paymentPlan != nil {
+		rvalue = debt.paymentPlan.resolveAmountToPay(debt.Amount)
+	}
+
+	return rvalue
+}
+
+//  evaluatedAsOf is the date the debt was evaluated as of, or the run's evaluation date if it hasn't been
+func (debt *Debt) evaluatedAsOf() CivilDate {
+	if debt.asOf.IsZero() {
+		return debt.currentSettings().evaluationDate()
+	}
+	return debt.asOf
+}
+
+//  currency is the currency the debt, and everything paid towards it, is in
+func (debt *Debt) currency() string {
+	return currencyOrDefault(debt.Currency)
+}
+
+//  hasBalanceComponents is true when the debt owes more than its principal, i.e. it has interest terms
+//  or a fee schedule
+func (debt *Debt) hasBalanceComponents() bool {
+	if debt.paymentPlan == nil {
+		return false
+	}
+	return debt.InterestTerms != nil || debt.paymentPlan.FeeSchedule != nil
+}
+
+//  remainingAmountAsOf determines how much was left to pay at the end of date
+func (debt *Debt) remainingAmountAsOf(date CivilDate) decimal.Decimal {
+	if debt.hasBalanceComponents() {
+		return debt.calculateBalances(date).remaining()
+	}
+
+	amountPaid := decimal.Zero
+	if debt.paymentPlan != nil {
+		for _, pmt := range debt.paymentPlan.payments {
+			if !pmt.date.After(date) {
+				amountPaid = amountPaid.Add(pmt.Amount)
+			}
+		}
+	}
+	return roundAmount(debt.amountToPay().Sub(amountPaid), debt.currency(), debt.currentSettings().rounding)
+}
+
+//  calculateRemainingAmount determines how much money is still left over in the debt. It fails when
+//  the payments can't be totalled, e.g. because they aren't all in the debt's currency
+func (debt *Debt) calculateRemainingAmount(updateObject bool) (decimal.Decimal, error) {
+	var balances *debtBalances
+
+	if debt.hasBalanceComponents() {
+		balances = debt.calculateBalances(debt.evaluatedAsOf())
+	}
+	return debt.remainingAmountFrom(balances, updateObject)
+}
+
+//  remainingAmountFrom works out the remaining amount using balances already replayed as of the
+//  evaluation date. Only debts with interest or fees need them
+func (debt *Debt) remainingAmountFrom(balances *debtBalances, updateObject bool) (decimal.Decimal, error) {
+	var rvalue decimal.Decimal
+
+	if debt.hasBalanceComponents() {
+		//  Debts with interest or fees owe whatever payments haven't covered of each
+		rvalue = balances.remaining()
+	} else {
+		//  See how much has been paid, if anything
+		amountPaid, _, err := debt.sumTotalPayments()
+
+		if err != nil {
+			return rvalue, fmt.Errorf("Unable to total payments:%v", err)
+		}
+
+		remaining, err := newMoney(debt.amountToPay(), debt.Currency).sub(amountPaid)
+
+		if err != nil {
+			return rvalue, fmt.Errorf("Unable to total payments:%v", err)
+		}
+		rvalue = remaining.round(debt.currentSettings().rounding).Amount
+	}
+
+	//  Anything paid beyond what was owed is a credit due back to them, not a negative balance
+	creditBalance := decimal.Zero
+	if rvalue.IsNegative() {
+		creditBalance = rvalue.Neg()
+		rvalue = decimal.Zero
+	}
+
+	//  Now set the remaining amount on the object
+	if updateObject {
+		debt.RemainingAmount = rvalue
+		debt.CreditBalance = creditBalance
+	}
+	return rvalue, nil
+}
+
+func (debt *Debt) isPaymentPlanActive() bool {
+	rc := false
+
+	if debt.paymentPlan != nil {
+		if !debt.isDebtPaidOff() {
+			rc = true
+		}
+	}
+	return rc
+}
+
+//  planActiveFrom is isPaymentPlanActive for a remaining amount that's already been worked out
+func (debt *Debt) planActiveFrom(remaining decimal.Decimal) bool {
+	return debt.paymentPlan != nil && remaining.IsPositive()
+}
+
+//  calculateNextPayemntDate calculates the next payment date from the plan's schedule: it is the due
+//  date of the oldest installment that payments haven't fully covered, however those payments were timed
+func (debt *Debt) calculateNextPaymentDate(updateObject bool) string {
+	remaining, _ := debt.calculateRemainingAmount(false)
+	return debt.nextPaymentDateFrom(remaining, updateObject)
+}
+
+//  nextPaymentDateFrom is calculateNextPaymentDate for a remaining amount that's already been worked out
+func (debt *Debt) nextPaymentDateFrom(remaining decimal.Decimal, updateObject bool) string {
+	var nextPaymentDate string
+
+	//  First make sure a payment plan is active
+	if debt.planActiveFrom(remaining) {
+		nextScheduledDate, ok := debt.paymentPlan.nextDueDate()
+
+		if !ok {
+			return nextPaymentDate
+		}
+		nextPaymentDate = nextScheduledDate.String()
+
+		if updateObject && len(nextPaymentDate) > 0 {
+			debt.NextPaymentDate = &nextPaymentDate
+		}
+
+	}
+
+	return nextPaymentDate
+}
+
+//  nextDueDate returns the due date of the oldest installment that isn't fully paid
+func (plan *PaymentPlan) nextDueDate() (CivilDate, bool) {
+	if idx := plan.firstUnpaidInstallment(); idx >= 0 {
+		return plan.schedule[idx].DueDate, true
+	}
+
+	if len(plan.schedule) > 0 {
+		//  Every installment is paid but there's still a balance, so carry on along
+		//  the plan's calendar
+		recurrence, err := parseRecurrence(plan.InstallmentFrequency)
+
+		if err != nil {
+			return CivilDate{}, false
+		}
+
+		return plan.nextDueDateAfter(recurrence, plan.schedule[len(plan.schedule)-1].DueDate), true
+	}
+
+	//  If we get here, there's no schedule to go on
+	return plan.currentSettings().roll.adjust(plan.startDate), true
+}
+
+//  Not used, but left-in for posterity- I did this before I re-read the spec and saw this important point-
+//  Payments made on days outside the expected payment schedule still go toward paying off the remaining_amount, but do not change/delay the payment schedule.
+func (debt *Debt) lastScheduledDateNotExceedingPaymentDate(date CivilDate) (CivilDate, error) {
+	var rvalue CivilDate
+	var err error = nil
+
+	if debt.isPaymentPlanActive() {
+		var recurrence Recurrence
+
+		recurrence, err = parseRecurrence(debt.paymentPlan.InstallmentFrequency)
+
+		if err == nil {
+			rvalue = recurrence.lastOnOrBefore(debt.paymentPlan.startDate, date)
+		}
+	}
+
+	return rvalue, err
+}
+
+//  effectiveMatchPolicy returns the plan's own match policy, falling back to the run's
+func (plan *PaymentPlan) effectiveMatchPolicy() MatchPolicy {
+	if plan.matchPolicy != nil {
+		return *plan.matchPolicy
+	}
+	return plan.currentSettings().matchPolicy
+}
+
+//  matchScheduledDate returns the scheduled date the match policy ties the payment to, if any. It
+//  flags payments in the timeline; allocation applies payments however they were timed
+func (plan *PaymentPlan) matchScheduledDate(pmt Payment) (CivilDate, bool) {
+	return plan.effectiveMatchPolicy().matchScheduledDate(pmt.date, plan.isPaymentDateAScheduledDate)
+}
+
+//  onTimeThrough is the last day the match policy lets an installment be paid on time. Delinquency,
+//  plan status and late fees all count from the day after it
+func (plan *PaymentPlan) onTimeThrough(installment Installment) CivilDate {
+	return installment.DueDate.addDays(plan.effectiveMatchPolicy().lateDays())
+}
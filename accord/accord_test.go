@@ -1,4 +1,4 @@
-package main
+package accord
 
 import (
 	"testing"
@@ -9,16 +9,89 @@ import (
 )
 
 func makeMockGraph(debts *map[int]Debt) error {
-	var plans map[int]PaymentPlan
-	var payments []Payment
+	return makeMockGraphWith(debts, testSettings())
+}
+
+//  makeMockGraphWith builds the mock data worked out with config
+func makeMockGraphWith(debts *map[int]Debt, config *settings) error {
 	var err error = nil
 
-	*debts, plans, payments = getRawTestObjects()
+	raw, plans, payments := getRawTestObjects()
 
-	err = normalizeData(*debts, plans, payments)
+	*debts, err = normalizeData(raw, plans, payments, config)
 
 	return err
 }
+
+//  remainingAmount is what's left to pay on the debt, failing the test if it can't be worked out
+func remainingAmount(t *testing.T, debt *Debt) decimal.Decimal {
+	rvalue, err := debt.calculateRemainingAmount(false)
+	if err != nil {
+		t.Errorf("calculateRemainingAmount(), unexpected error: %v", err)
+	}
+	return rvalue
+}
+
+//  testClock is the clock the tests run by, so their results don't depend on the day they run
+var testClock = fixedClock{now: time.Date(2021, time.June, 30, 12, 0, 0, 0, time.UTC)}
+
+//  testSettings is a copy of the default settings running by testClock, for a test to change
+func testSettings() *settings {
+	rvalue := *defaultSettings
+	rvalue.clock = testClock
+	return &rvalue
+}
+
+//  testPlan is a $400 plan for debt 1 paying $100 a week from Monday 2021-01-04, for a test to change
+func testPlan() PaymentPlan {
+	return PaymentPlan{ID: 1, DebtID: 1, AmountToPay: decimal.NewFromInt(400), InstallmentFrequency: "weekly", InstallmentAmount: decimal.NewFromInt(100), StartDate: "2021-01-04"}
+}
+
+//  testPayment is a settled payment of amount on date
+func testPayment(date string, amount int64) Payment {
+	return Payment{Amount: decimal.NewFromInt(amount), Date: date}
+}
+
+//  makeTestDebt puts plan and payments on debt and works it out as of asOf the way normalizeData does,
+//  so the test sees what a run would. The plan's settings are used for the debt too. asOf is required
+//  so that results don't depend on the day the tests run
+func makeTestDebt(t *testing.T, debt Debt, plan PaymentPlan, payments []Payment, asOf string) Debt {
+	var err error = nil
+
+	if len(asOf) == 0 {
+		t.Fatalf("makeTestDebt(), want:an as-of date, got:none")
+	}
+	date := mustParseDate(t, asOf)
+
+	if err = debt.Normalize(); err != nil {
+		t.Fatalf("Normalize(), unexpected error: %v", err)
+	}
+	if err = plan.Normalize(); err != nil {
+		t.Fatalf("Normalize(), unexpected error: %v", err)
+	}
+	if err = plan.validateAmountToPay(); err != nil {
+		t.Fatalf("validateAmountToPay(), unexpected error: %v", err)
+	}
+
+	transactions := make([]Payment, len(payments))
+	for idx, pmt := range payments {
+		pmt.PaymentPlanID = plan.ID
+		if err = pmt.Normalize(time.UTC); err != nil {
+			t.Fatalf("Normalize(), unexpected error: %v", err)
+		}
+		transactions[idx] = pmt
+	}
+
+	debt.settings = plan.settings
+	debt.paymentPlan = &plan
+	debt.paymentPlan.debtAmount = debt.Amount
+	debt.paymentPlan.transactions = groupPaymentsByPlan(transactions, date)[plan.ID]
+
+	if err = debt.evaluate(date); err != nil {
+		t.Fatalf("evaluate(), unexpected error: %v", err)
+	}
+	return debt
+}
 func getRawTestObjects() (debtTestData map[int]Debt, paymentPlanTestData map[int]PaymentPlan, paymentsTestData []Payment) {
 	debtTestData = map[int]Debt{
 		0:  Debt{Amount: decimal.NewFromFloat(1500000.00), ID: 0},
@@ -88,14 +161,14 @@ func getRawTestObjects() (debtTestData map[int]Debt, paymentPlanTestData map[int
 
 	for key, plan := range paymentPlanTestData {
 		if len(plan.StartDate) > 0 {
-			plan.startDate, _ = time.Parse(isoDateLayout, plan.StartDate)
+			plan.startDate, _ = ParseCivilDate(plan.StartDate)
 			paymentPlanTestData[key] = plan
 		}
 	}
 
 	for idx, pmt := range paymentsTestData {
 		if len(pmt.Date) > 0 {
-			pmt.date, _ = time.Parse(isoDateLayout, pmt.Date)
+			pmt.date, _ = parseBusinessDate(pmt.Date, time.UTC)
 			paymentsTestData[idx] = pmt
 		}
 	}
@@ -119,7 +192,7 @@ func TestDebt_calculateRemainingAmount(t *testing.T) {
 	//  Check to make sure we pick up amount from paymentplan rather than debt
 	t.Logf("Checking that we fall back to PaymentPlan for a payment amount")
 	debt := debts[0]
-	got = debt.calculateRemainingAmount(false)
+	got = remainingAmount(t, &debt)
 	want, err = decimal.NewFromString("1000000")
 	if err != nil {
 		t.Errorf("calculateRemainingAmount(), error converting decimal from string mock data: %v", err)
@@ -132,7 +205,7 @@ func TestDebt_calculateRemainingAmount(t *testing.T) {
 	//  Check to make sure we pick up amount from paymentplan rather than debt
 	t.Logf("Checking a paymentplan that has a zero in 'amount_to_pay'")
 	debt = debts[1]
-	got = debt.calculateRemainingAmount(false)
+	got = remainingAmount(t, &debt)
 	want, err = decimal.NewFromString("1184")
 	if err != nil {
 		t.Errorf("calculateRemainingAmount(), error converting decimal from string mock data: %v", err)
@@ -144,7 +217,7 @@ func TestDebt_calculateRemainingAmount(t *testing.T) {
 	//  Check a bunch of payments
 	t.Logf("Checking a bunch of payments")
 	debt = debts[4]
-	got = debt.calculateRemainingAmount(false)
+	got = remainingAmount(t, &debt)
 	want, err = decimal.NewFromString("44.26")
 	if err != nil {
 		t.Errorf("calculateRemainingAmount(), error converting decimal from string mock data: %v", err)
@@ -156,19 +229,22 @@ func TestDebt_calculateRemainingAmount(t *testing.T) {
 	//  Check a debt that should be paid off but there was an extra payment
 	t.Logf("Check a debt that should be paid off but there was an extra payment")
 	debt = debts[9]
-	got = debt.calculateRemainingAmount(false)
-	want, err = decimal.NewFromString("-100.00")
+	got = remainingAmount(t, &debt)
+	want, err = decimal.NewFromString("0.00")
 	if err != nil {
 		t.Errorf("calculateRemainingAmount(), error converting decimal from string mock data: %v", err)
 	}
 	if !want.Equal(got) {
 		t.Errorf("calculateRemainingAmount(), want:%v, got:%v", want, got)
 	}
+	if want = decimal.NewFromInt(100); !debt.CreditBalance.Equal(want) {
+		t.Errorf("calculateRemainingAmount() credit balance, want:%v, got:%v", want, debt.CreditBalance)
+	}
 
 	//  Check debt with no payment plan
 	t.Logf("Check debt with no payment plan")
 	debt = debts[10]
-	got = debt.calculateRemainingAmount(false)
+	got = remainingAmount(t, &debt)
 	want, err = decimal.NewFromString("10000.00")
 	if err != nil {
 		t.Errorf("calculateRemainingAmount(), error converting decimal from string mock data: %v", err)
@@ -192,7 +268,8 @@ func TestDebt_calculateNextPaymentDate(t *testing.T) {
 	}
 
 	//  Test for date that occurs before plan begins. This should never happen,
-	//  but lots of things should never happen but do.
+	//  but lots of things should never happen but do. The $125 still pays the first
+	//  five $25 installments ahead
 	t.Logf("Checking the next scheduled date when payments occur before the start date")
 	debt := debts[11]
 	dateString = debt.calculateNextPaymentDate(false)
@@ -200,11 +277,13 @@ func TestDebt_calculateNextPaymentDate(t *testing.T) {
 	if err != nil {
 		t.Errorf("calculateNextPaymentDate() error parsing date returned from calculateNextPaymentDate (%v):%v", dateString, err)
 	}
-	want, err = time.Parse(isoDateLayout, "2020-11-05")
+	want, err = time.Parse(isoDateLayout, "2020-12-10")
 	if got != want {
 		t.Errorf("Got:%v but wanted %v", got, want)
 	}
 
+	//  $15,727.39 covers 52 of the $300 installments and part of the 53rd,
+	//  however oddly the payments were timed
 	t.Logf("Trying to confuse the next-date algorithm")
 	debt = debts[2]
 	dateString = debt.calculateNextPaymentDate(false)
@@ -212,11 +291,12 @@ func TestDebt_calculateNextPaymentDate(t *testing.T) {
 	if err != nil {
 		t.Errorf("calculateNextPaymentDate() error parsing date returned from calculateNextPaymentDate (%v):%v", dateString, err)
 	}
-	want, err = time.Parse(isoDateLayout, "2021-04-15")
+	want, err = time.Parse(isoDateLayout, "2022-05-26")
 	if got != want {
 		t.Errorf("Got:%v but wanted %v", got, want)
 	}
 
+	//  None of the payments fall on a due date, but $145 still covers five $25 installments
 	t.Logf("Checking no payments made on correct date")
 	debt = debts[3]
 	dateString = debt.calculateNextPaymentDate(false)
@@ -224,7 +304,7 @@ func TestDebt_calculateNextPaymentDate(t *testing.T) {
 	if err != nil {
 		t.Errorf("calculateNextPaymentDate() error parsing date returned from calculateNextPaymentDate (%v):%v", dateString, err)
 	}
-	want, err = time.Parse(isoDateLayout, "2020-10-21")
+	want, err = time.Parse(isoDateLayout, "2020-11-25")
 	if got != want {
 		t.Errorf("Got:%v but wanted %v", got, want)
 	}
@@ -259,4 +339,56 @@ func TestDebt_isDebtPaidOff(t *testing.T) {
 	if got != want {
 		t.Errorf("Testing isDebtPaidOff  Got:%v, Want:%v", got, want)
 	}
+
+	//  Asking doesn't change the debt, and the answer doesn't depend on what was asked first
+	t.Logf("Checking the answer doesn't depend on call order")
+	debt = debts[6]
+	debt.RemainingAmount = decimal.Zero
+	before := debt
+	if got = debt.isDebtPaidOff(); got != want {
+		t.Errorf("isDebtPaidOff() after clearing RemainingAmount, want:%v, got:%v", want, got)
+	}
+	if remaining := remainingAmount(t, &debt); !remaining.IsPositive() {
+		t.Errorf("calculateRemainingAmount(false), want:a positive amount, got:%v", remaining)
+	}
+	if !debt.RemainingAmount.Equal(before.RemainingAmount) {
+		t.Errorf("isDebtPaidOff(), want:the debt left alone, got:remaining %v", debt.RemainingAmount)
+	}
+}
+
+func TestGroupPaymentsByPlan(t *testing.T) {
+	payments := []Payment{
+		{ID: 1, PaymentPlanID: 7, date: CivilDate{2021, 1, 11}},
+		{ID: 2, PaymentPlanID: 3, date: CivilDate{2021, 1, 4}},
+		{ID: 3, PaymentPlanID: 7, date: CivilDate{2021, 1, 4}},
+		{ID: 4, PaymentPlanID: 7, date: CivilDate{2021, 2, 1}},
+		{ID: 5, PaymentPlanID: 7, date: CivilDate{2021, 1, 4}},
+	}
+
+	groups := groupPaymentsByPlan(payments, CivilDate{2021, 1, 31})
+
+	t.Logf("Checking each plan gets its payments up to the as-of date, in date order")
+	want := map[int][]int{7: {3, 5, 1}, 3: {2}}
+	if len(groups) != len(want) {
+		t.Fatalf("groupPaymentsByPlan(), want:%v plans, got:%v", len(want), len(groups))
+	}
+	for planId, ids := range want {
+		group := groups[planId]
+		if len(group) != len(ids) {
+			t.Errorf("groupPaymentsByPlan() plan %v, want:%v payments, got:%v", planId, len(ids), len(group))
+			continue
+		}
+		for idx := range ids {
+			if group[idx].ID != ids[idx] {
+				t.Errorf("groupPaymentsByPlan() plan %v payment %v, want:%v, got:%v", planId, idx, ids[idx], group[idx].ID)
+			}
+		}
+	}
+
+	t.Logf("Checking the groups can't run into each other")
+	for planId, group := range groups {
+		if cap(group) != len(group) {
+			t.Errorf("groupPaymentsByPlan() plan %v, want:cap %v, got:%v", planId, len(group), cap(group))
+		}
+	}
 }
@@ -0,0 +1,110 @@
+package accord
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	installmentPaid    string = "paid"
+	installmentPartial string = "partial"
+	installmentUnpaid  string = "unpaid"
+)
+
+//  allocatePayments applies payments, oldest first, against the installments of a schedule, also
+//  oldest first. A payment covers as much of the current installment as it can and carries whatever
+//  is left forward to the next one, so a short payment leaves an installment partial and an overpayment
+//  pays ahead. Payments dated after through are left out; a zero through applies them all.
+//  A copy of the schedule is returned with AmountPaid, Status and PaidOn filled in, the schedule
+//  passed in isn't touched
+func allocatePayments(schedule []Installment, payments []Payment, through CivilDate) []Installment {
+	rvalue := make([]Installment, len(schedule))
+	for idx, installment := range schedule {
+		installment.AmountPaid = decimal.Zero
+		installment.Status = installmentUnpaid
+		installment.PaidOn = nil
+		rvalue[idx] = installment
+	}
+
+	//  Payments usually arrive sorted and all up to the through date, in which case they're used as they
+	//  are. Don't count on it though
+	ordered := payments
+	inOrder := sort.SliceIsSorted(payments, func(i, j int) bool { return payments[i].date.Before(payments[j].date) })
+	if !inOrder || (!through.IsZero() && len(payments) > 0 && payments[len(payments)-1].date.After(through)) {
+		ordered = make([]Payment, 0, len(payments))
+		for _, pmt := range payments {
+			if through.IsZero() || !pmt.date.After(through) {
+				ordered = append(ordered, pmt)
+			}
+		}
+		sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].date.Before(ordered[j].date) })
+	}
+
+	//  Paid installments are always the front of the schedule, so their paid-on dates are collected in
+	//  order and kept together rather than allocated one installment at a time
+	paidOn := make([]CivilDate, 0, len(ordered))
+	current := 0
+	for _, pmt := range ordered {
+		available := pmt.Amount
+
+		for current < len(rvalue) && available.IsPositive() {
+			installment := &rvalue[current]
+
+			//  Most payments pay an installment off exactly, which needs no arithmetic
+			unpaid := installment.unpaid()
+
+			if available.LessThan(unpaid) {
+				if installment.Status == installmentPartial {
+					installment.AmountPaid = installment.AmountPaid.Add(available)
+				} else {
+					installment.AmountPaid = available
+				}
+				installment.Status = installmentPartial
+				break
+			}
+
+			paidOn = append(paidOn, pmt.date)
+			installment.AmountPaid = installment.AmountDue
+			installment.Status = installmentPaid
+			current++
+			if available.Equal(unpaid) {
+				break
+			}
+			available = available.Sub(unpaid)
+		}
+	}
+
+	for idx := range paidOn {
+		rvalue[idx].PaidOn = &paidOn[idx]
+	}
+	return rvalue
+}
+
+//  allocatePayments applies the principal part of the plan's payments to its schedule. Whatever a
+//  payment put toward fees and interest doesn't pay an installment
+func (plan *PaymentPlan) allocatePayments(balances *debtBalances) {
+	plan.schedule = allocatePayments(plan.schedule, balances.principal, CivilDate{})
+}
+
+//  unpaid is what is still owed on the installment
+func (installment Installment) unpaid() decimal.Decimal {
+	switch installment.Status {
+	case installmentPaid:
+		return decimal.Zero
+	case installmentPartial:
+		return installment.AmountDue.Sub(installment.AmountPaid)
+	}
+	return installment.AmountDue
+}
+
+//  firstUnpaidInstallment returns the index of the oldest installment that isn't fully paid,
+//  or -1 when every installment is
+func (plan *PaymentPlan) firstUnpaidInstallment() int {
+	for idx, installment := range plan.schedule {
+		if installment.Status != installmentPaid {
+			return idx
+		}
+	}
+	return -1
+}
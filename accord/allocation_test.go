@@ -0,0 +1,96 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPaymentPlan_allocatePayments(t *testing.T) {
+	tests := []struct {
+		description string
+		payments    []Payment
+		wantStatus  []string
+		wantPaid    []string
+		wantNext    string
+	}{
+		{"a $1 payment on the due date doesn't keep the plan on track",
+			[]Payment{testPayment("2021-01-04", 1)},
+			[]string{installmentPartial, installmentUnpaid, installmentUnpaid, installmentUnpaid},
+			[]string{"1", "0", "0", "0"}, "2021-01-04"},
+		{"a full payment a day late pays the installment",
+			[]Payment{testPayment("2021-01-05", 100)},
+			[]string{installmentPaid, installmentUnpaid, installmentUnpaid, installmentUnpaid},
+			[]string{"100", "0", "0", "0"}, "2021-01-11"},
+		{"partial payments add up",
+			[]Payment{testPayment("2021-01-04", 60), testPayment("2021-01-06", 40), testPayment("2021-01-11", 30)},
+			[]string{installmentPaid, installmentPartial, installmentUnpaid, installmentUnpaid},
+			[]string{"100", "30", "0", "0"}, "2021-01-11"},
+		{"an overpayment carries forward",
+			[]Payment{testPayment("2021-01-04", 250)},
+			[]string{installmentPaid, installmentPaid, installmentPartial, installmentUnpaid},
+			[]string{"100", "100", "50", "0"}, "2021-01-18"},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v", test.description)
+		debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, testPlan(), test.payments, "2021-12-31")
+		for idx, installment := range debt.paymentPlan.schedule {
+			if installment.Status != test.wantStatus[idx] {
+				t.Errorf("allocatePayments() installment %v status, want:%v, got:%v", installment.Sequence, test.wantStatus[idx], installment.Status)
+			}
+			if want := decimal.RequireFromString(test.wantPaid[idx]); !installment.AmountPaid.Equal(want) {
+				t.Errorf("allocatePayments() installment %v paid, want:%v, got:%v", installment.Sequence, want, installment.AmountPaid)
+			}
+		}
+
+		if got := debt.calculateNextPaymentDate(false); got != test.wantNext {
+			t.Errorf("calculateNextPaymentDate(), want:%v, got:%v", test.wantNext, got)
+		}
+	}
+
+	t.Logf("Checking the paid-on date is the payment that finished the installment")
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, testPlan(), []Payment{testPayment("2021-01-04", 60), testPayment("2021-01-06", 40)}, "2021-12-31")
+	plan := debt.paymentPlan
+	if plan.schedule[0].PaidOn == nil || plan.schedule[0].PaidOn.String() != "2021-01-06" {
+		t.Errorf("allocatePayments() paid on, want:2021-01-06, got:%v", plan.schedule[0].PaidOn)
+	}
+
+	t.Logf("Checking payments after the through date are left out")
+	schedule := allocatePayments(plan.schedule, plan.payments, mustParseDate(t, "2021-01-05"))
+	if schedule[0].Status != installmentPartial || !schedule[0].AmountPaid.Equal(decimal.NewFromInt(60)) {
+		t.Errorf("allocatePayments() through 2021-01-05, want partial 60, got:%v %v", schedule[0].Status, schedule[0].AmountPaid)
+	}
+}
+
+func TestPaymentPlan_allocatePayments_fees(t *testing.T) {
+	//  The second installment is missed by a day, so the $25 late fee charged on 2021-01-12 comes out of
+	//  that day's $100 before principal does and every exact payment after it falls $25 short
+	plan := testPlan()
+	plan.FeeSchedule = &FeeSchedule{LateFee: decimal.NewFromInt(25)}
+	payments := []Payment{testPayment("2021-01-04", 100), testPayment("2021-01-12", 100), testPayment("2021-01-18", 100)}
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, plan, payments, "2021-01-19")
+
+	t.Logf("Checking installments are only paid with principal")
+	wantStatus := []string{installmentPaid, installmentPaid, installmentPartial, installmentUnpaid}
+	wantPaid := []string{"100", "100", "75", "0"}
+	for idx, installment := range debt.paymentPlan.schedule {
+		if installment.Status != wantStatus[idx] {
+			t.Errorf("allocatePayments() installment %v status, want:%v, got:%v", installment.Sequence, wantStatus[idx], installment.Status)
+		}
+		if want := decimal.RequireFromString(wantPaid[idx]); !installment.AmountPaid.Equal(want) {
+			t.Errorf("allocatePayments() installment %v paid, want:%v, got:%v", installment.Sequence, want, installment.AmountPaid)
+		}
+	}
+	if paidOn := debt.paymentPlan.schedule[1].PaidOn; paidOn == nil || paidOn.String() != "2021-01-18" {
+		t.Errorf("allocatePayments() paid on, want:2021-01-18, got:%v", paidOn)
+	}
+
+	t.Logf("Checking the short installment is missed and charged a late fee")
+	if debt.Delinquency.MissedInstallments != 1 || !debt.Delinquency.ArrearsAmount.Equal(decimal.NewFromInt(25)) {
+		t.Errorf("calculateDelinquency(), want:1 missed with 25 in arrears, got:%v %v", debt.Delinquency.MissedInstallments, debt.Delinquency.ArrearsAmount)
+	}
+	if !debt.Fees.FeesAssessed.Equal(decimal.NewFromInt(50)) || len(debt.Fees.Assessments) != 2 {
+		t.Errorf("calculateFees(), want:50 in two late fees, got:%v %v", debt.Fees.FeesAssessed, debt.Fees.Assessments)
+	}
+}
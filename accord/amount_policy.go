@@ -0,0 +1,48 @@
+package accord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+//  What a payment plan with an amount_to_pay of zero means
+const (
+	zeroAmountFullDebt string = "full_debt" //  The plan didn't say, so the whole debt is owed
+	zeroAmountForgiven string = "forgiven"  //  Nothing is owed; the debt is forgiven in full
+	zeroAmountReject   string = "reject"    //  The plan is invalid
+)
+
+//  parseZeroAmountPolicy reads a zero amount_to_pay policy: full_debt, forgiven or reject
+func parseZeroAmountPolicy(value string) (string, error) {
+	switch policy := strings.ToLower(strings.TrimSpace(value)); policy {
+	case zeroAmountFullDebt, zeroAmountForgiven, zeroAmountReject:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("Received unexpected zero amount_to_pay policy %v; expected full_debt, forgiven or reject", value)
+	}
+}
+
+//  resolveAmountToPay is what the plan actually asks for, applying the zero amount policy when its
+//  amount_to_pay is zero
+func (plan *PaymentPlan) resolveAmountToPay(debtAmount decimal.Decimal) decimal.Decimal {
+	if !plan.AmountToPay.IsZero() {
+		return plan.AmountToPay
+	}
+	if plan.currentSettings().zeroAmountPolicy == zeroAmountForgiven {
+		return decimal.Zero
+	}
+	return debtAmount
+}
+
+//  validateAmountToPay refuses a plan with an amount_to_pay of zero under the reject policy
+func (plan *PaymentPlan) validateAmountToPay() error {
+	if plan.AmountToPay.IsZero() && plan.currentSettings().zeroAmountPolicy == zeroAmountReject {
+		return fmt.Errorf("Payment plan %v has an amount_to_pay of zero", plan.ID)
+	}
+	if plan.AmountToPay.IsNegative() {
+		return fmt.Errorf("Payment plan %v has a negative amount_to_pay of %v", plan.ID, plan.AmountToPay)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPaymentPlan_resolveAmountToPay(t *testing.T) {
+	tests := []struct {
+		policy       string
+		amountToPay  int64
+		want         int64
+		wantSchedule int
+	}{
+		{zeroAmountFullDebt, 0, 500, 5},
+		{zeroAmountForgiven, 0, 0, 0},
+		{zeroAmountForgiven, 300, 300, 3},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking an amount_to_pay of %v under %v", test.amountToPay, test.policy)
+		config := testSettings()
+		config.zeroAmountPolicy = test.policy
+
+		plan := PaymentPlan{ID: 1, DebtID: 1, AmountToPay: decimal.NewFromInt(test.amountToPay), InstallmentFrequency: "weekly", InstallmentAmount: decimal.NewFromInt(100), StartDate: "2021-01-04", settings: config}
+		plan.startDate = mustParseDate(t, plan.StartDate)
+		plan.debtAmount = decimal.NewFromInt(500)
+		debt := Debt{ID: 1, Amount: decimal.NewFromInt(500), paymentPlan: &plan}
+
+		if got := debt.amountToPay(); !got.Equal(decimal.NewFromInt(test.want)) {
+			t.Errorf("amountToPay(), want:%v, got:%v", test.want, got)
+		}
+
+		plan.generatePaymentSchedule()
+		if len(plan.schedule) != test.wantSchedule {
+			t.Errorf("generatePaymentSchedule(), want:%v installments, got:%v", test.wantSchedule, len(plan.schedule))
+		}
+	}
+}
+
+func TestNormalizeData_zeroAmountPolicy(t *testing.T) {
+	t.Logf("Checking the policy is recorded on debts whose plan has an amount_to_pay of zero")
+	config := testSettings()
+	config.zeroAmountPolicy = zeroAmountForgiven
+	var debts map[int]Debt
+	if err := makeMockGraphWith(&debts, config); err != nil {
+		t.Fatalf("normalizeData(), unexpected error: %v", err)
+	}
+	if debts[1].ZeroAmountPolicy != zeroAmountForgiven || debts[4].ZeroAmountPolicy != "" {
+		t.Errorf("normalizeData(), want the policy on debt 1 only, got:%q and %q", debts[1].ZeroAmountPolicy, debts[4].ZeroAmountPolicy)
+	}
+	debt := debts[1]
+	if !debt.RemainingAmount.IsZero() || debt.Forgiven == nil {
+		t.Errorf("normalizeData(), want debt 1 forgiven, got remaining:%v", debt.RemainingAmount)
+	}
+
+	t.Logf("Checking the reject policy refuses them")
+	config.zeroAmountPolicy = zeroAmountReject
+	if err := makeMockGraphWith(&debts, config); err == nil {
+		t.Errorf("normalizeData() expected an error")
+	}
+}
+
+func TestParseZeroAmountPolicy(t *testing.T) {
+	if got, err := parseZeroAmountPolicy("Forgiven"); err != nil || got != zeroAmountForgiven {
+		t.Errorf("parseZeroAmountPolicy(), want:%v, got:%v %v", zeroAmountForgiven, got, err)
+	}
+	if _, err := parseZeroAmountPolicy("ignore"); err == nil {
+		t.Errorf("parseZeroAmountPolicy() expected an error")
+	}
+}
@@ -0,0 +1,167 @@
+package accord
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//  CivilDate is a calendar date with no time-of-day or location. Payment schedules
+//  are about days, not instants, so doing schedule arithmetic on CivilDate keeps us clear
+//  of DST transitions and of two time.Time values for the same day failing to compare
+//  equal because they carry different locations.
+type CivilDate struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+//  civilDateOf returns the date t falls on in t's own location
+func civilDateOf(t time.Time) CivilDate {
+	year, month, day := t.Date()
+	return CivilDate{Year: year, Month: month, Day: day}
+}
+
+//  ParseCivilDate parses an ISO (YYYY-MM-DD) date
+func ParseCivilDate(value string) (CivilDate, error) {
+	t, err := time.Parse(isoDateLayout, value)
+	if err != nil {
+		return CivilDate{}, err
+	}
+	return civilDateOf(t), nil
+}
+
+//  parseBusinessDate parses either a plain ISO date or an RFC 3339 timestamp. Timestamps are
+//  converted to the business time zone first so that a payment made at 11pm Central on the 1st
+//  belongs to the 1st, even though it was the 2nd in UTC
+func parseBusinessDate(value string, loc *time.Location) (CivilDate, error) {
+	value = strings.TrimSpace(value)
+	if len(value) == len(isoDateLayout) {
+		return ParseCivilDate(value)
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return CivilDate{}, fmt.Errorf("Unable to parse date %v:%v", value, err)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return civilDateOf(t.In(loc)), nil
+}
+
+//  midnightUTC returns the start of the day in UTC; UTC has no DST, so every day is exactly 24 hours
+func (d CivilDate) midnightUTC() time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC)
+}
+
+//  In returns the start of the day in the given location
+func (d CivilDate) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+//  String formats the date as YYYY-MM-DD
+func (d CivilDate) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, int(d.Month), d.Day)
+}
+
+//  MarshalText lets dates appear in JSON as YYYY-MM-DD
+func (d CivilDate) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+//  UnmarshalText reads a YYYY-MM-DD date
+func (d *CivilDate) UnmarshalText(text []byte) error {
+	var err error = nil
+	*d, err = ParseCivilDate(string(text))
+	return err
+}
+
+//  IsZero reports whether the date is unset
+func (d CivilDate) IsZero() bool {
+	return d == CivilDate{}
+}
+
+//  Before reports whether d comes before other
+func (d CivilDate) Before(other CivilDate) bool {
+	if d.Year != other.Year {
+		return d.Year < other.Year
+	}
+	if d.Month != other.Month {
+		return d.Month < other.Month
+	}
+	return d.Day < other.Day
+}
+
+//  After reports whether d comes after other
+func (d CivilDate) After(other CivilDate) bool {
+	return other.Before(d)
+}
+
+//  Weekday returns the day of the week d falls on; 1970-01-01 was a Thursday
+func (d CivilDate) Weekday() time.Weekday {
+	weekday := (d.dayNumber() + int(time.Thursday)) % 7
+	if weekday < 0 {
+		weekday += 7
+	}
+	return time.Weekday(weekday)
+}
+
+//  addDays moves the date by n days, which may be negative
+func (d CivilDate) addDays(n int) CivilDate {
+	if n == 0 {
+		return d
+	}
+	return civilDateFromDayNumber(d.dayNumber() + n)
+}
+
+//  daysSince counts the days from other to d; it is negative when other comes after d. It counts whole
+//  days rather than going through time.Duration, which can't hold more than about 292 years
+func (d CivilDate) daysSince(other CivilDate) int {
+	return d.dayNumber() - other.dayNumber()
+}
+
+//  dayNumber counts the days from 1970-01-01 to d in the proleptic Gregorian calendar, counting years
+//  from March so the leap day comes last
+func (d CivilDate) dayNumber() int {
+	year := d.Year
+	if d.Month <= time.February {
+		year--
+	}
+	era := year / 400
+	if year < 0 {
+		era = (year - 399) / 400
+	}
+	yearOfEra := year - era*400
+	dayOfYear := (153*((int(d.Month)+9)%12)+2)/5 + d.Day - 1
+	dayOfEra := yearOfEra*365 + yearOfEra/4 - yearOfEra/100 + dayOfYear
+	return era*146097 + dayOfEra - 719468
+}
+
+//  civilDateFromDayNumber is the date dayNumber days after 1970-01-01, the inverse of dayNumber
+func civilDateFromDayNumber(dayNumber int) CivilDate {
+	days := dayNumber + 719468
+	era := days / 146097
+	if days < 0 {
+		era = (days - 146096) / 146097
+	}
+	dayOfEra := days - era*146097
+	yearOfEra := (dayOfEra - dayOfEra/1460 + dayOfEra/36524 - dayOfEra/146096) / 365
+	dayOfYear := dayOfEra - (365*yearOfEra + yearOfEra/4 - yearOfEra/100)
+	shiftedMonth := (5*dayOfYear + 2) / 153
+	day := dayOfYear - (153*shiftedMonth+2)/5 + 1
+	month := shiftedMonth + 3
+	year := yearOfEra + era*400
+	if month > 12 {
+		month -= 12
+		year++
+	}
+	return CivilDate{Year: year, Month: time.Month(month), Day: day}
+}
+
+//  addMonthsClamped moves the date forward by the given number of months and lands on day,
+//  clamped to the last day of the resulting month
+func (d CivilDate) addMonthsClamped(months int, day int) CivilDate {
+	firstOfMonth := time.Date(d.Year, d.Month+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	return CivilDate{Year: firstOfMonth.Year(), Month: firstOfMonth.Month(), Day: clampDay(firstOfMonth.Year(), firstOfMonth.Month(), day)}
+}
@@ -0,0 +1,79 @@
+package accord
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBusinessDate(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatalf("error loading time zone:%v", err)
+	}
+
+	tests := []struct {
+		description string
+		value       string
+		loc         *time.Location
+		want        string
+	}{
+		{"a plain ISO date ignores the time zone", "2021-03-14", chicago, "2021-03-14"},
+		{"a late evening payment belongs to the business day", "2021-03-02T04:30:00Z", chicago, "2021-03-01"},
+		{"the same payment in UTC falls on the next day", "2021-03-02T04:30:00Z", time.UTC, "2021-03-02"},
+		{"a timestamp carrying its own offset", "2021-11-07T23:59:00-06:00", chicago, "2021-11-07"},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v", test.description)
+		got, err := parseBusinessDate(test.value, test.loc)
+		if err != nil {
+			t.Errorf("parseBusinessDate(%v) unexpected error:%v", test.value, err)
+			continue
+		}
+		if got.String() != test.want {
+			t.Errorf("parseBusinessDate(), want:%v, got:%v", test.want, got)
+		}
+	}
+}
+
+func TestCivilDate_acrossDST(t *testing.T) {
+	//  2021-03-14 is only 23 hours long in Chicago, which used to knock
+	//  hour-based schedule arithmetic off by a day
+	t.Logf("Checking day arithmetic across the spring-forward transition")
+	start := mustParseDate(t, "2021-03-13")
+	got := start.addDays(7)
+	want := mustParseDate(t, "2021-03-20")
+	if got != want {
+		t.Errorf("addDays(), want:%v, got:%v", want, got)
+	}
+	if days := want.daysSince(start); days != 7 {
+		t.Errorf("daysSince(), want:7, got:%v", days)
+	}
+
+	t.Logf("Checking day counts further apart than a time.Duration can hold")
+	if days := mustParseDate(t, "2000-01-01").daysSince(mustParseDate(t, "1600-01-01")); days != 146097 {
+		t.Errorf("daysSince(), want:146097, got:%v", days)
+	}
+	if days := mustParseDate(t, "1600-03-01").daysSince(mustParseDate(t, "2021-03-01")); days != -153767 {
+		t.Errorf("daysSince(), want:-153767, got:%v", days)
+	}
+	for date := mustParseDate(t, "1899-12-25"); date.Year < 2101; date = date.addDays(13) {
+		if want := int(date.midnightUTC().Unix() / 86400); date.dayNumber() != want {
+			t.Errorf("dayNumber(%v), want:%v, got:%v", date, want, date.dayNumber())
+		}
+		if want := civilDateOf(date.midnightUTC().AddDate(0, 0, 13)); date.addDays(13) != want {
+			t.Errorf("addDays(%v, 13), want:%v, got:%v", date, want, date.addDays(13))
+		}
+		if want := date.midnightUTC().Weekday(); date.Weekday() != want {
+			t.Errorf("Weekday(%v), want:%v, got:%v", date, want, date.Weekday())
+		}
+	}
+
+	t.Logf("Checking a weekly schedule stays on the same weekday across DST")
+	recurrence, _ := parseRecurrence("weekly")
+	for n := 0; n < 10; n++ {
+		if d := recurrence.occurrence(start, n); d.Weekday() != time.Saturday {
+			t.Errorf("occurrence(%v) landed on %v (%v), want Saturday", n, d.Weekday(), d)
+		}
+	}
+}
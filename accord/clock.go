@@ -0,0 +1,29 @@
+package accord
+
+import (
+	"time"
+)
+
+//  Clock supplies the current time. Anything embedding these calculations can swap in its own
+//  clock instead of relying on the machine's
+type Clock interface {
+	Now() time.Time
+}
+
+//  systemClock is the machine's clock
+type systemClock struct{}
+
+//  Now returns the current time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+//  fixedClock always returns the same instant, which is what tests and replays want
+type fixedClock struct {
+	now time.Time
+}
+
+//  Now returns the fixed instant
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
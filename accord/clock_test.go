@@ -0,0 +1,67 @@
+package accord
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestEvaluationDate(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatalf("error loading time zone:%v", err)
+	}
+
+	t.Logf("Checking the clock's date is taken in the business time zone")
+	config := testSettings()
+	config.clock = fixedClock{now: time.Date(2021, 3, 2, 4, 30, 0, 0, time.UTC)}
+	config.location = chicago
+	if got := config.evaluationDate(); got.String() != "2021-03-01" {
+		t.Errorf("evaluationDate(), want:2021-03-01, got:%v", got)
+	}
+
+	t.Logf("Checking an as-of date wins over the clock")
+	config.asOf = mustParseDate(t, "2020-06-28")
+	if got := config.evaluationDate(); got.String() != "2020-06-28" {
+		t.Errorf("evaluationDate(), want:2020-06-28, got:%v", got)
+	}
+}
+
+func TestNormalizeData_asOf(t *testing.T) {
+	t.Logf("Checking payments after the as-of date are ignored")
+	config := testSettings()
+	config.asOf = mustParseDate(t, "2021-03-31")
+	var debts map[int]Debt
+	if err := makeMockGraphWith(&debts, config); err != nil {
+		t.Fatalf("normalizeData(), error making mock data: %v", err)
+	}
+
+	debt := debts[2]
+	got := remainingAmount(t, &debt)
+	want := decimal.RequireFromString("36273.32")
+	if !want.Equal(got) {
+		t.Errorf("calculateRemainingAmount(), want:%v, got:%v", want, got)
+	}
+
+	//  Without the whopping payment of 2021-04-01, $5,726.68 only covers 19 installments
+	next := debt.calculateNextPaymentDate(false)
+	if next != "2021-02-18" {
+		t.Errorf("calculateNextPaymentDate(), want:2021-02-18, got:%v", next)
+	}
+
+	t.Logf("Checking the remaining amount early in a plan")
+	config = testSettings()
+	config.asOf = mustParseDate(t, "2020-04-01")
+	if err := makeMockGraphWith(&debts, config); err != nil {
+		t.Fatalf("normalizeData(), error making mock data: %v", err)
+	}
+	debt = debts[4]
+	want = decimal.RequireFromString("107.62")
+	if !debt.RemainingAmount.Equal(want) {
+		t.Errorf("normalizeData() remaining amount, want:%v, got:%v", want, debt.RemainingAmount)
+	}
+	if !debt.InPaymentPlan {
+		t.Errorf("normalizeData() expected the plan to be active as of %v", config.asOf)
+	}
+}
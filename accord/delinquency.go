@@ -0,0 +1,81 @@
+package accord
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+const (
+	agingCurrent string = "current"
+	aging1To30   string = "1-30"
+	aging31To60  string = "31-60"
+	aging61To90  string = "61-90"
+	agingOver90  string = "90+"
+)
+
+//  Delinquency describes how far behind a debtor is on their payment plan as of a date
+type Delinquency struct {
+	AsOf               CivilDate       `json:"as_of"`
+	MissedInstallments int             `json:"missed_installments"` //  Installments no longer on time by AsOf that payments haven't covered
+	ArrearsAmount      decimal.Decimal `json:"arrears_amount"`      //  What is still owed on the missed installments
+	DaysPastDue        int             `json:"days_past_due"`       //  Days since the oldest uncovered installment fell due
+	AgingBucket        string          `json:"aging_bucket"`        //  current, 1-30, 31-60, 61-90 or 90+
+}
+
+//  calculateDelinquency works out missed installments, arrears and days past due as of a date.
+//  An installment only counts as missed once the match policy's on-time window after its due date
+//  has passed, so something due on asOf itself isn't late yet. Days past due still count from the
+//  due date. The principal part of payments made on or before asOf is allocated to
+//  installments in due date order, which means an early or partial payment still counts toward the
+//  oldest installment
+func (debt *Debt) calculateDelinquency(asOf CivilDate, updateObject bool) *Delinquency {
+	if debt.paymentPlan == nil {
+		return nil
+	}
+
+	rvalue := &Delinquency{AsOf: asOf, ArrearsAmount: decimal.Zero, AgingBucket: agingCurrent}
+
+	//  The schedule is already allocated through the day the debt was evaluated as of
+	schedule := debt.paymentPlan.schedule
+	if asOf != debt.asOf {
+		schedule = allocatePayments(schedule, debt.calculateBalances(asOf).principal, asOf)
+	}
+
+	for _, installment := range schedule {
+		if !debt.paymentPlan.onTimeThrough(installment).Before(asOf) {
+			break
+		}
+		if installment.Status == installmentPaid {
+			continue
+		}
+
+		if rvalue.MissedInstallments == 0 {
+			rvalue.DaysPastDue = asOf.daysSince(installment.DueDate)
+		}
+		rvalue.MissedInstallments++
+		rvalue.ArrearsAmount = addAmount(rvalue.ArrearsAmount, installment.unpaid())
+	}
+
+	rvalue.ArrearsAmount = roundAmount(rvalue.ArrearsAmount, debt.currency(), debt.currentSettings().rounding)
+	rvalue.AgingBucket = agingBucket(rvalue.DaysPastDue)
+
+	if updateObject {
+		debt.Delinquency = rvalue
+	}
+	return rvalue
+}
+
+//  agingBucket groups days past due the way collections reports them
+func agingBucket(daysPastDue int) string {
+	switch {
+	case daysPastDue <= 0:
+		return agingCurrent
+	case daysPastDue <= 30:
+		return aging1To30
+	case daysPastDue <= 60:
+		return aging31To60
+	case daysPastDue <= 90:
+		return aging61To90
+	default:
+		return agingOver90
+	}
+}
@@ -0,0 +1,59 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDebt_calculateDelinquency(t *testing.T) {
+	//  $100 a week from Monday 2021-01-04: they pay the first installment, half of the second
+	//  and then nothing until a catch-up payment in March
+	plan := testPlan()
+	plan.AmountToPay = decimal.NewFromInt(1000)
+	payments := []Payment{testPayment("2021-01-04", 100), testPayment("2021-01-11", 50), testPayment("2021-03-10", 200)}
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(1000)}, plan, payments, "2021-12-31")
+
+	tests := []struct {
+		description string
+		asOf        string
+		wantMissed  int
+		wantArrears string
+		wantDays    int
+		wantBucket  string
+	}{
+		{"nothing is late on the first due date", "2021-01-04", 0, "0", 0, agingCurrent},
+		{"a partial payment leaves the installment missed", "2021-01-12", 1, "50", 1, aging1To30},
+		{"missed installments pile up", "2021-02-01", 3, "250", 21, aging1To30},
+		{"a future payment doesn't count yet", "2021-03-09", 9, "850", 57, aging31To60},
+		{"a catch-up payment covers the oldest installments first", "2021-03-10", 7, "650", 44, aging31To60},
+		{"the oldest installment ages past 60 days", "2021-04-15", 7, "650", 80, aging61To90},
+		{"the oldest installment ages past 90 days", "2021-05-15", 7, "650", 110, agingOver90},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v as of %v", test.description, test.asOf)
+		got := debt.calculateDelinquency(mustParseDate(t, test.asOf), false)
+		if got == nil {
+			t.Fatalf("calculateDelinquency() returned nothing for a debt with a plan")
+		}
+		if got.MissedInstallments != test.wantMissed {
+			t.Errorf("calculateDelinquency() missed installments, want:%v, got:%v", test.wantMissed, got.MissedInstallments)
+		}
+		if want := decimal.RequireFromString(test.wantArrears); !got.ArrearsAmount.Equal(want) {
+			t.Errorf("calculateDelinquency() arrears, want:%v, got:%v", want, got.ArrearsAmount)
+		}
+		if got.DaysPastDue != test.wantDays {
+			t.Errorf("calculateDelinquency() days past due, want:%v, got:%v", test.wantDays, got.DaysPastDue)
+		}
+		if got.AgingBucket != test.wantBucket {
+			t.Errorf("calculateDelinquency() aging bucket, want:%v, got:%v", test.wantBucket, got.AgingBucket)
+		}
+	}
+
+	t.Logf("Checking a debt without a plan has no delinquency")
+	noPlan := Debt{ID: 2, Amount: decimal.NewFromInt(500)}
+	if got := noPlan.calculateDelinquency(mustParseDate(t, "2021-05-15"), false); got != nil {
+		t.Errorf("calculateDelinquency(), want nil, got:%+v", got)
+	}
+}
@@ -0,0 +1,244 @@
+package accord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	//  LiveRun stands in for a run id to mean what the services return now
+	LiveRun string = "live"
+)
+
+//  Change categories, in the order they're reported
+const (
+	changeNewDebt       string = "new_debt"
+	changeRemovedDebt   string = "removed_debt"
+	changePaidOff       string = "paid_off"
+	changeNewPlan       string = "new_plan"
+	changeMissedPayment string = "missed_payment"
+	changeNextDueDate   string = "next_due_date_changed"
+	changeStatus        string = "status_changed"
+	changeOther         string = "changed" //  Something changed that none of the other categories cover
+)
+
+var changeCategories = []string{changeNewDebt, changeRemovedDebt, changePaidOff, changeNewPlan, changeMissedPayment, changeNextDueDate, changeStatus, changeOther}
+
+//  FieldChange is a debt field's value in each run, as JSON. A field a run didn't have is null
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+//  DebtChange is how a debt changed between the runs
+type DebtChange struct {
+	DebtID     int           `json:"debt_id"`
+	Categories []string      `json:"categories"`
+	Changes    []FieldChange `json:"changes"`
+}
+
+//  DiffReport is every debt that changed between two runs, and how many fell in each category
+type DiffReport struct {
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Summary map[string]int `json:"summary"`
+	Debts   []DebtChange   `json:"debts"` //  In debt id order
+}
+
+//  evaluationFields are the parts of a debt's fields that only say when the run worked it out, which
+//  changes every run, so they're left out of the comparison
+var evaluationFields = map[string][]string{
+	"delinquency": {"as_of"},
+}
+
+//  debtFields is the debt's JSON output split into its fields, less its evaluationFields
+func debtFields(debt *Debt) (map[string]json.RawMessage, error) {
+	rvalue := make(map[string]json.RawMessage)
+	if debt == nil {
+		return rvalue, nil
+	}
+
+	encoded, err := json.Marshal(debt)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to marshal debt %v:%v", debt.ID, err)
+	}
+	err = json.Unmarshal(encoded, &rvalue)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read debt %v:%v", debt.ID, err)
+	}
+
+	for name, skipped := range evaluationFields {
+		value, ok := rvalue[name]
+		if !ok || bytes.Equal(value, []byte("null")) {
+			continue
+		}
+		var parts map[string]json.RawMessage
+		if err = json.Unmarshal(value, &parts); err != nil {
+			return nil, fmt.Errorf("Unable to read debt %v's %v:%v", debt.ID, name, err)
+		}
+		for _, part := range skipped {
+			delete(parts, part)
+		}
+		if rvalue[name], err = json.Marshal(parts); err != nil {
+			return nil, fmt.Errorf("Unable to marshal debt %v's %v:%v", debt.ID, name, err)
+		}
+	}
+	return rvalue, nil
+}
+
+//  diffDebt compares a debt's output in two runs, field by field. Either can be nil for a debt
+//  only one of the runs has. It returns nil when nothing changed
+func diffDebt(from *Debt, to *Debt) (*DebtChange, error) {
+	fromFields, err := debtFields(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := debtFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(fromFields)+len(toFields))
+	for name := range fromFields {
+		names = append(names, name)
+	}
+	for name := range toFields {
+		if _, ok := fromFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	null := json.RawMessage("null")
+	var changes []FieldChange
+	for _, name := range names {
+		oldValue, ok := fromFields[name]
+		if !ok {
+			oldValue = null
+		}
+		newValue, ok := toFields[name]
+		if !ok {
+			newValue = null
+		}
+		if !bytes.Equal(oldValue, newValue) {
+			changes = append(changes, FieldChange{Field: name, Old: oldValue, New: newValue})
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	rvalue := DebtChange{Changes: changes}
+	switch {
+	case from == nil:
+		rvalue.DebtID = to.ID
+		rvalue.Categories = []string{changeNewDebt}
+		return &rvalue, nil
+	case to == nil:
+		rvalue.DebtID = from.ID
+		rvalue.Categories = []string{changeRemovedDebt}
+		return &rvalue, nil
+	}
+
+	rvalue.DebtID = to.ID
+	if from.RemainingAmount.IsPositive() && to.RemainingAmount.IsZero() {
+		rvalue.Categories = append(rvalue.Categories, changePaidOff)
+	}
+	if len(from.PlanStatus) == 0 && len(to.PlanStatus) > 0 {
+		rvalue.Categories = append(rvalue.Categories, changeNewPlan)
+	}
+	if missedInstallments(to) > missedInstallments(from) {
+		rvalue.Categories = append(rvalue.Categories, changeMissedPayment)
+	}
+	if !bytes.Equal(fromFields["next_payment_due_date"], toFields["next_payment_due_date"]) {
+		rvalue.Categories = append(rvalue.Categories, changeNextDueDate)
+	}
+	if from.PlanStatus != to.PlanStatus && len(from.PlanStatus) > 0 {
+		rvalue.Categories = append(rvalue.Categories, changeStatus)
+	}
+	if len(rvalue.Categories) == 0 {
+		rvalue.Categories = []string{changeOther}
+	}
+	return &rvalue, nil
+}
+
+//  missedInstallments is how many installments the debt had missed, zero for debts without a plan
+func missedInstallments(debt *Debt) int {
+	if debt.Delinquency == nil {
+		return 0
+	}
+	return debt.Delinquency.MissedInstallments
+}
+
+//  BuildDiffReport compares the debts two runs worked out
+func BuildDiffReport(fromID string, from []Debt, toID string, to []Debt) (DiffReport, error) {
+	rvalue := DiffReport{From: fromID, To: toID, Summary: make(map[string]int), Debts: make([]DebtChange, 0)}
+
+	byID := func(debts []Debt) map[int]*Debt {
+		indexed := make(map[int]*Debt, len(debts))
+		for idx := range debts {
+			indexed[debts[idx].ID] = &debts[idx]
+		}
+		return indexed
+	}
+	fromDebts := byID(from)
+	toDebts := byID(to)
+
+	var ids []int
+	for id := range fromDebts {
+		ids = append(ids, id)
+	}
+	for id := range toDebts {
+		if _, ok := fromDebts[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		change, err := diffDebt(fromDebts[id], toDebts[id])
+		if err != nil {
+			return rvalue, err
+		}
+		if change == nil {
+			continue
+		}
+		for _, category := range change.Categories {
+			rvalue.Summary[category]++
+		}
+		rvalue.Debts = append(rvalue.Debts, *change)
+	}
+	return rvalue, nil
+}
+
+//  WriteDiffText writes the report for people: the count in each category, then each debt that changed
+//  with its fields' old and new values
+func WriteDiffText(w io.Writer, report DiffReport) error {
+	var text strings.Builder
+
+	fmt.Fprintf(&text, "Changes from %v to %v\n", report.From, report.To)
+	if len(report.Debts) == 0 {
+		fmt.Fprintf(&text, "No changes\n")
+	}
+	for _, category := range changeCategories {
+		if count := report.Summary[category]; count > 0 {
+			fmt.Fprintf(&text, "  %v: %v\n", category, count)
+		}
+	}
+
+	for _, change := range report.Debts {
+		fmt.Fprintf(&text, "\nDebt %v: %v\n", change.DebtID, strings.Join(change.Categories, ", "))
+		for _, field := range change.Changes {
+			fmt.Fprintf(&text, "  %v: %s -> %s\n", field.Field, field.Old, field.New)
+		}
+	}
+
+	_, err := io.WriteString(w, text.String())
+	return err
+}
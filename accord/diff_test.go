@@ -0,0 +1,126 @@
+package accord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBuildDiffReport(t *testing.T) {
+	dueDate := "2021-01-11"
+	laterDueDate := "2021-01-18"
+
+	from := []Debt{
+		{ID: 1, Amount: decimal.NewFromInt(100), RemainingAmount: decimal.NewFromInt(50), PlanStatus: statusActive, NextPaymentDate: &dueDate},
+		{ID: 2, Amount: decimal.NewFromInt(200), RemainingAmount: decimal.NewFromInt(200)},
+		{ID: 3, Amount: decimal.NewFromInt(300), RemainingAmount: decimal.NewFromInt(300), PlanStatus: statusActive, NextPaymentDate: &dueDate},
+		{ID: 4, Amount: decimal.NewFromInt(400), RemainingAmount: decimal.NewFromInt(400)},
+		{ID: 5, Amount: decimal.NewFromInt(500), RemainingAmount: decimal.NewFromInt(500)},
+	}
+	to := []Debt{
+		{ID: 1, Amount: decimal.NewFromInt(100), RemainingAmount: decimal.Zero, PlanStatus: statusCompleted},
+		{ID: 2, Amount: decimal.NewFromInt(200), RemainingAmount: decimal.NewFromInt(200), PlanStatus: statusActive, NextPaymentDate: &laterDueDate},
+		{ID: 3, Amount: decimal.NewFromInt(300), RemainingAmount: decimal.NewFromInt(300), PlanStatus: statusDelinquent, NextPaymentDate: &dueDate,
+			Delinquency: &Delinquency{MissedInstallments: 1}},
+		{ID: 4, Amount: decimal.NewFromInt(400), RemainingAmount: decimal.NewFromInt(400)},
+		{ID: 6, Amount: decimal.NewFromInt(600), RemainingAmount: decimal.NewFromInt(600)},
+	}
+
+	report, err := BuildDiffReport("yesterday", from, LiveRun, to)
+	if err != nil {
+		t.Fatalf("BuildDiffReport(), want:no error, got:%v", err)
+	}
+
+	tests := []struct {
+		debtID     int
+		categories string
+	}{
+		{1, "paid_off,next_due_date_changed,status_changed"},
+		{2, "new_plan,next_due_date_changed"},
+		{3, "missed_payment,status_changed"},
+		{5, "removed_debt"},
+		{6, "new_debt"},
+	}
+
+	if len(report.Debts) != len(tests) {
+		t.Fatalf("BuildDiffReport(), want:%v changed debts, got:%v", len(tests), report.Debts)
+	}
+
+	for idx, test := range tests {
+		t.Logf("Checking debt %v", test.debtID)
+		change := report.Debts[idx]
+		if change.DebtID != test.debtID || strings.Join(change.Categories, ",") != test.categories {
+			t.Errorf("BuildDiffReport(), want:%v %v, got:%v %v", test.debtID, test.categories, change.DebtID, change.Categories)
+		}
+	}
+
+	t.Logf("Checking the summary")
+	if report.Summary[changeStatus] != 2 || report.Summary[changePaidOff] != 1 {
+		t.Errorf("BuildDiffReport() summary, want:2 status changes and 1 paid off, got:%v", report.Summary)
+	}
+
+	t.Logf("Checking the old and new values of a field")
+	var remaining *FieldChange
+	for idx := range report.Debts[0].Changes {
+		if report.Debts[0].Changes[idx].Field == "remaining_amount" {
+			remaining = &report.Debts[0].Changes[idx]
+		}
+	}
+	//  Decimals are written however the program has decimal's JSON set up, quoted by default
+	fifty, _ := json.Marshal(decimal.NewFromInt(50))
+	zero, _ := json.Marshal(decimal.Zero)
+	if remaining == nil || string(remaining.Old) != string(fifty) || string(remaining.New) != string(zero) {
+		t.Errorf("BuildDiffReport() remaining_amount, want:%s -> %s, got:%v", fifty, zero, remaining)
+	}
+
+	t.Logf("Checking the text report")
+	var buffer bytes.Buffer
+	err = WriteDiffText(&buffer, report)
+	if err != nil || !strings.Contains(buffer.String(), "Debt 1: paid_off") || !strings.Contains(buffer.String(), fmt.Sprintf("  remaining_amount: %s -> %s\n", fifty, zero)) {
+		t.Errorf("WriteDiffText(), want:debt 1 paid off, got:%v %v", buffer.String(), err)
+	}
+}
+
+func TestBuildDiffReport_consecutiveRuns(t *testing.T) {
+	//  A plan that's up to date between due dates, one already paid off and a debt without a plan, so
+	//  nothing about them changes from one day to the next
+	inputs := Inputs{
+		Debts: []Debt{{ID: 1, Amount: decimal.NewFromInt(400)}, {ID: 2, Amount: decimal.NewFromInt(100)}, {ID: 3, Amount: decimal.NewFromInt(50)}},
+		PaymentPlans: []PaymentPlan{
+			{ID: 1, DebtID: 1, AmountToPay: decimal.NewFromInt(400), InstallmentFrequency: "WEEKLY", InstallmentAmount: decimal.NewFromInt(100), StartDate: "2021-01-04"},
+			{ID: 2, DebtID: 2, AmountToPay: decimal.NewFromInt(100), InstallmentFrequency: "WEEKLY", InstallmentAmount: decimal.NewFromInt(100), StartDate: "2021-01-04"},
+		},
+		Payments: []Payment{
+			{ID: 1, PaymentPlanID: 1, Amount: decimal.NewFromInt(100), Date: "2021-01-04"},
+			{ID: 2, PaymentPlanID: 1, Amount: decimal.NewFromInt(100), Date: "2021-01-11"},
+			{ID: 3, PaymentPlanID: 2, Amount: decimal.NewFromInt(100), Date: "2021-01-04"},
+		},
+	}
+
+	var runs [][]Debt
+	for _, asOf := range []string{"2021-01-12", "2021-01-13"} {
+		options := DefaultOptions()
+		options.AsOf = mustParseDate(t, asOf)
+		portfolio, err := ComputePortfolio(inputs, options)
+		if err != nil {
+			t.Fatalf("ComputePortfolio(), want:no error, got:%v", err)
+		}
+		runs = append(runs, portfolio.DebtList(nil))
+	}
+	if runs[0][0].Delinquency == nil || runs[0][0].Delinquency.AsOf == runs[1][0].Delinquency.AsOf {
+		t.Fatalf("ComputePortfolio(), want:delinquency worked out as of each run's date, got:%+v %+v", runs[0][0].Delinquency, runs[1][0].Delinquency)
+	}
+
+	t.Logf("Checking runs a day apart on the same inputs have no changes")
+	report, err := BuildDiffReport("2021-01-12", runs[0], "2021-01-13", runs[1])
+	if err != nil {
+		t.Fatalf("BuildDiffReport(), want:no error, got:%v", err)
+	}
+	if len(report.Debts) != 0 {
+		t.Errorf("BuildDiffReport(), want:no changes, got:%+v", report.Debts)
+	}
+}
@@ -0,0 +1,143 @@
+package accord
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+//  Debt event types
+const (
+	eventDebtCreated     string = "DebtCreated"
+	eventPlanCreated     string = "PlanCreated"
+	eventPaymentReceived string = "PaymentReceived" //  A payment record, whatever its status
+	eventPaymentReversed string = "PaymentReversed" //  A reversal, chargeback or refund of an earlier payment
+	eventPlanCompleted   string = "PlanCompleted"   //  Derived from the others; replaying it changes nothing
+)
+
+//  DebtDetails is what a DebtCreated event knows about the debt
+type DebtDetails struct {
+	Amount        decimal.Decimal `json:"amount"`
+	Currency      string          `json:"currency"`
+	InterestTerms *InterestTerms  `json:"interest_terms,omitempty"`
+	settings      *settings       //  What the debt was worked out with, so a replay works it out the same way
+}
+
+//  DebtEvent is something that happened to a debt. Folding a debt's events in sequence order rebuilds it
+type DebtEvent struct {
+	Sequence int          `json:"sequence"` //  Position in the debt's stream, from 1
+	Type     string       `json:"type"`
+	DebtID   int          `json:"debt_id"`
+	Date     CivilDate    `json:"date"`
+	Debt     *DebtDetails `json:"debt,omitempty"`    //  For DebtCreated
+	Plan     *PaymentPlan `json:"plan,omitempty"`    //  For PlanCreated
+	Payment  *Payment     `json:"payment,omitempty"` //  For PaymentReceived and PaymentReversed
+}
+
+//  debtEvents derives the debt's event stream from the data we fetched. The debt and its plan are
+//  created on the plan's start date, or on the first payment if that came sooner; a debt without a
+//  plan is created on the date it was evaluated as of
+func (debt *Debt) debtEvents() []DebtEvent {
+	var rvalue []DebtEvent
+
+	created := debt.evaluatedAsOf()
+	if debt.paymentPlan != nil {
+		created = debt.paymentPlan.startDate
+		for _, pmt := range debt.paymentPlan.transactions {
+			if pmt.date.Before(created) {
+				created = pmt.date
+			}
+		}
+	}
+
+	rvalue = append(rvalue, DebtEvent{
+		Type:   eventDebtCreated,
+		DebtID: debt.ID,
+		Date:   created,
+		Debt:   &DebtDetails{Amount: debt.Amount, Currency: debt.currency(), InterestTerms: debt.InterestTerms, settings: debt.settings},
+	})
+
+	if debt.paymentPlan != nil {
+		plan := *debt.paymentPlan
+		rvalue = append(rvalue, DebtEvent{Type: eventPlanCreated, DebtID: debt.ID, Date: created, Plan: &plan})
+
+		for idx := range debt.paymentPlan.transactions {
+			pmt := debt.paymentPlan.transactions[idx]
+			eventType := eventPaymentReceived
+			if pmt.paymentType() != paymentTypePayment {
+				eventType = eventPaymentReversed
+			}
+			rvalue = append(rvalue, DebtEvent{Type: eventType, DebtID: debt.ID, Date: pmt.date, Payment: &pmt})
+		}
+
+		if completedOn, ok := debt.completedOn(); ok {
+			rvalue = append(rvalue, DebtEvent{Type: eventPlanCompleted, DebtID: debt.ID, Date: completedOn})
+		}
+	}
+
+	//  Stable, so the creation events stay first and a plan completes after the payment that completed it
+	sort.SliceStable(rvalue, func(i, j int) bool { return rvalue[i].Date.Before(rvalue[j].Date) })
+	for idx := range rvalue {
+		rvalue[idx].Sequence = idx + 1
+	}
+	return rvalue
+}
+
+//  applyEvent folds a single event into the debt
+func (debt *Debt) applyEvent(event DebtEvent) {
+	switch event.Type {
+	case eventDebtCreated:
+		*debt = Debt{ID: event.DebtID, Amount: event.Debt.Amount, Currency: event.Debt.Currency, InterestTerms: event.Debt.InterestTerms, settings: event.Debt.settings}
+	case eventPlanCreated:
+		plan := *event.Plan
+		plan.debtAmount = debt.Amount
+		plan.transactions = nil
+		plan.payments = nil
+		plan.returnedPayments = nil
+		plan.schedule = nil
+		debt.paymentPlan = &plan
+	case eventPaymentReceived, eventPaymentReversed:
+		debt.paymentPlan.transactions = append(debt.paymentPlan.transactions, *event.Payment)
+	}
+}
+
+//  replayDebtEvents rebuilds a debt from the events that had happened by asOf and works out its state as of then
+func replayDebtEvents(events []DebtEvent, asOf CivilDate) (Debt, error) {
+	var rvalue Debt
+
+	for _, event := range events {
+		if event.Date.After(asOf) {
+			break
+		}
+		rvalue.applyEvent(event)
+	}
+	err := rvalue.evaluate(asOf)
+	return rvalue, err
+}
+
+//  replayDebtEventsTo rebuilds a debt from its events up to and including the one with the given sequence,
+//  and works out its state as of that event's date
+func replayDebtEventsTo(events []DebtEvent, sequence int) (Debt, error) {
+	if sequence < 1 || sequence > len(events) {
+		return Debt{}, fmt.Errorf("Received event %v; expected 1 to %v", sequence, len(events))
+	}
+
+	var rvalue Debt
+	for _, event := range events[:sequence] {
+		rvalue.applyEvent(event)
+	}
+	err := rvalue.evaluate(events[sequence-1].Date)
+	return rvalue, err
+}
+
+//  buildHistory lists every debt's events, in debt id order
+func buildHistory(debts map[int]Debt) []DebtEvent {
+	rvalue := make([]DebtEvent, 0)
+
+	for _, id := range sortedDebtIDs(debts) {
+		debt := debts[id]
+		rvalue = append(rvalue, debt.debtEvents()...)
+	}
+	return rvalue
+}
@@ -0,0 +1,89 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestReplayDebtEvents_matchesNormalizeData(t *testing.T) {
+	var debts map[int]Debt
+
+	err := makeMockGraph(&debts)
+	if err != nil {
+		t.Fatalf("replayDebtEvents(), error making mock data: %v", err)
+	}
+
+	for _, id := range sortedDebtIDs(debts) {
+		debt := debts[id]
+		t.Logf("Checking debt %v", id)
+
+		got, err := replayDebtEvents(debt.debtEvents(), debt.evaluatedAsOf())
+		if err != nil {
+			t.Errorf("replayDebtEvents(), unexpected error: %v", err)
+		}
+		if !got.RemainingAmount.Equal(debt.RemainingAmount) || !got.CreditBalance.Equal(debt.CreditBalance) || got.PlanStatus != debt.PlanStatus {
+			t.Errorf("replayDebtEvents(), want:%v/%v/%v, got:%v/%v/%v", debt.RemainingAmount, debt.CreditBalance, debt.PlanStatus, got.RemainingAmount, got.CreditBalance, got.PlanStatus)
+		}
+		if (got.NextPaymentDate == nil) != (debt.NextPaymentDate == nil) || (got.NextPaymentDate != nil && *got.NextPaymentDate != *debt.NextPaymentDate) {
+			t.Errorf("replayDebtEvents() next payment date, want:%v, got:%v", debt.NextPaymentDate, got.NextPaymentDate)
+		}
+	}
+}
+
+func TestDebt_debtEvents(t *testing.T) {
+	original := 2
+	plan := testPlan()
+	plan.AmountToPay = decimal.NewFromInt(300)
+	payments := []Payment{
+		{ID: 1, Amount: decimal.NewFromInt(100), Date: "2021-01-04"},
+		{ID: 2, Amount: decimal.NewFromInt(100), Date: "2021-01-11"},
+		{ID: 3, Amount: decimal.NewFromInt(100), Date: "2021-01-13", Type: paymentTypeReversal, OriginalPaymentID: &original},
+		{ID: 4, Amount: decimal.NewFromInt(200), Date: "2021-01-18"},
+	}
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(300)}, plan, payments, "2021-02-01")
+
+	events := debt.debtEvents()
+
+	t.Logf("Checking the event stream")
+	want := []string{eventDebtCreated, eventPlanCreated, eventPaymentReceived, eventPaymentReceived, eventPaymentReversed, eventPaymentReceived, eventPlanCompleted}
+	if len(events) != len(want) {
+		t.Fatalf("debtEvents(), want:%v, got:%v", want, events)
+	}
+	for idx := range want {
+		if events[idx].Type != want[idx] || events[idx].Sequence != idx+1 {
+			t.Errorf("debtEvents() event %v, want:%v, got:%v (sequence %v)", idx+1, want[idx], events[idx].Type, events[idx].Sequence)
+		}
+	}
+	if events[6].Date.String() != "2021-01-18" {
+		t.Errorf("debtEvents() completed, want:2021-01-18, got:%v", events[6].Date)
+	}
+
+	tests := []struct {
+		description string
+		sequence    int
+		want        int64
+	}{
+		{"after the second payment", 4, 100},
+		{"after the reversal reinstates it", 5, 200},
+		{"after the last payment", 6, 0},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking the state %v", test.description)
+		got, err := replayDebtEventsTo(events, test.sequence)
+		if err != nil || !got.RemainingAmount.Equal(decimal.NewFromInt(test.want)) {
+			t.Errorf("replayDebtEventsTo(%v), want:%v, got:%v %v", test.sequence, test.want, got.RemainingAmount, err)
+		}
+	}
+
+	t.Logf("Checking the state at a date")
+	got, err := replayDebtEvents(events, mustParseDate(t, "2021-01-12"))
+	if err != nil || !got.RemainingAmount.Equal(decimal.NewFromInt(100)) || got.PlanStatus != statusActive {
+		t.Errorf("replayDebtEvents(), want:100 and active, got:%v and %v %v", got.RemainingAmount, got.PlanStatus, err)
+	}
+
+	if _, err := replayDebtEventsTo(events, 8); err == nil {
+		t.Errorf("replayDebtEventsTo() expected an error for an event that doesn't exist")
+	}
+}
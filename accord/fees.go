@@ -0,0 +1,114 @@
+package accord
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	feeTypeLate            string = "late"
+	feeTypeReturnedPayment string = "returned_payment"
+)
+
+//  FeeSchedule is the optional set of fees a plan charges
+type FeeSchedule struct {
+	LateFee            decimal.Decimal `json:"late_fee"`             //  Flat fee for each installment still unpaid once the grace window is over
+	GraceDays          int             `json:"grace_days"`           //  Days after the on-time window an installment can be paid without a late fee
+	ReturnedPaymentFee decimal.Decimal `json:"returned_payment_fee"` //  Flat fee for each payment that is returned
+	FeeCapPercent      decimal.Decimal `json:"fee_cap_percent"`      //  Total fees can't exceed this percentage of the amount to pay; zero for no cap
+}
+
+//  FeeAssessment is a single fee charged to the plan
+type FeeAssessment struct {
+	Type        string          `json:"type"`
+	Date        CivilDate       `json:"date"`
+	Amount      decimal.Decimal `json:"amount"`
+	Installment int             `json:"installment,omitempty"` //  Sequence of the installment a late fee is for
+}
+
+//  FeeBreakdown is what has been charged in fees, and how much of it has been paid
+type FeeBreakdown struct {
+	FeesAssessed    decimal.Decimal `json:"fees_assessed"`
+	FeesPaid        decimal.Decimal `json:"fees_paid"`
+	FeesOutstanding decimal.Decimal `json:"fees_outstanding"`
+	Assessments     []FeeAssessment `json:"assessments,omitempty"`
+}
+
+//  validate makes sure the fee schedule makes sense
+func (fees *FeeSchedule) validate() error {
+	if fees.LateFee.IsNegative() || fees.ReturnedPaymentFee.IsNegative() || fees.FeeCapPercent.IsNegative() {
+		return fmt.Errorf("Received a negative fee")
+	}
+	if fees.GraceDays < 0 {
+		return fmt.Errorf("Received a negative grace window of %v days", fees.GraceDays)
+	}
+	return nil
+}
+
+//  feesDue lists the fees the plan could charge up to asOf, in date order: a late fee for each
+//  installment once GraceDays have passed after its on-time window, and a returned payment fee for
+//  each payment that came back. Whether a late fee is charged depends on how much principal payments
+//  had covered by then, and the cap can cut fees short, so calculateBalances decides both as it
+//  replays the plan
+func (plan *PaymentPlan) feesDue(asOf CivilDate) []FeeAssessment {
+	var rvalue []FeeAssessment
+
+	fees := plan.FeeSchedule
+	if fees == nil {
+		return rvalue
+	}
+
+	if fees.LateFee.IsPositive() {
+		for _, installment := range plan.schedule {
+			assessedOn := plan.onTimeThrough(installment).addDays(fees.GraceDays + 1)
+			if assessedOn.After(asOf) {
+				break
+			}
+			rvalue = append(rvalue, FeeAssessment{Type: feeTypeLate, Date: assessedOn, Amount: fees.LateFee, Installment: installment.Sequence})
+		}
+	}
+
+	if fees.ReturnedPaymentFee.IsPositive() {
+		for _, pmt := range plan.returnedPayments {
+			if !pmt.date.After(asOf) {
+				rvalue = append(rvalue, FeeAssessment{Type: feeTypeReturnedPayment, Date: pmt.date, Amount: fees.ReturnedPaymentFee})
+			}
+		}
+	}
+
+	sort.SliceStable(rvalue, func(i, j int) bool { return rvalue[i].Date.Before(rvalue[j].Date) })
+	return rvalue
+}
+
+//  feeCap is the most the plan can charge in fees altogether, and false when there's no cap
+func (plan *PaymentPlan) feeCap(amountToPay Money) (decimal.Decimal, bool) {
+	if plan.FeeSchedule == nil || !plan.FeeSchedule.FeeCapPercent.IsPositive() {
+		return decimal.Zero, false
+	}
+	return roundAmount(amountToPay.Amount.Mul(plan.FeeSchedule.FeeCapPercent).Div(decimal.NewFromInt(100)), amountToPay.Currency, plan.currentSettings().rounding), true
+}
+
+//  calculateFees works out the fees charged up to asOf and how much of them payments have
+//  covered. Returns nil when the plan has no fee schedule
+func (debt *Debt) calculateFees(asOf CivilDate, updateObject bool) *FeeBreakdown {
+	if debt.paymentPlan == nil || debt.paymentPlan.FeeSchedule == nil {
+		return nil
+	}
+	return debt.feesFrom(debt.calculateBalances(asOf), updateObject)
+}
+
+//  feesFrom takes the fee breakdown from balances already replayed
+func (debt *Debt) feesFrom(balances *debtBalances, updateObject bool) *FeeBreakdown {
+	if debt.paymentPlan == nil || debt.paymentPlan.FeeSchedule == nil || balances == nil {
+		return nil
+	}
+
+	rvalue := &balances.fees
+
+	if updateObject {
+		debt.Fees = rvalue
+	}
+	return rvalue
+}
@@ -0,0 +1,70 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDebt_calculateBalances_fees(t *testing.T) {
+	fees := FeeSchedule{LateFee: decimal.NewFromInt(25), GraceDays: 3, ReturnedPaymentFee: decimal.NewFromInt(30)}
+
+	//  The second installment is paid inside its grace window so isn't charged, the third and fourth
+	//  never are and a payment comes back in between
+	plan := testPlan()
+	plan.FeeSchedule = &fees
+	returned := testPayment("2021-01-20", 100)
+	returned.Status = paymentFailed
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, plan, []Payment{testPayment("2021-01-04", 100), testPayment("2021-01-13", 100), returned}, "2021-12-31")
+	asOf := mustParseDate(t, "2021-01-31")
+
+	want := []FeeAssessment{
+		{feeTypeReturnedPayment, mustParseDate(t, "2021-01-20"), decimal.NewFromInt(30), 0},
+		{feeTypeLate, mustParseDate(t, "2021-01-22"), decimal.NewFromInt(25), 3},
+		{feeTypeLate, mustParseDate(t, "2021-01-29"), decimal.NewFromInt(25), 4},
+	}
+
+	check := func(got []FeeAssessment) {
+		if len(got) != len(want) {
+			t.Fatalf("calculateBalances(), want:%v, got:%v", want, got)
+		}
+		for idx := range want {
+			if got[idx].Type != want[idx].Type || got[idx].Date != want[idx].Date || !got[idx].Amount.Equal(want[idx].Amount) || got[idx].Installment != want[idx].Installment {
+				t.Errorf("calculateBalances() fee %v, want:%v, got:%v", idx, want[idx], got[idx])
+			}
+		}
+	}
+
+	t.Logf("Checking late and returned payment fees")
+	check(debt.calculateBalances(asOf).fees.Assessments)
+
+	//  15% of $400 leaves room for $60 of fees
+	t.Logf("Checking fees stop at the cap")
+	debt.paymentPlan.FeeSchedule.FeeCapPercent = decimal.NewFromInt(15)
+	want[2].Amount = decimal.NewFromInt(5)
+	check(debt.calculateBalances(asOf).fees.Assessments)
+
+	t.Logf("Checking nothing is charged before the grace window ends")
+	if got := debt.calculateBalances(mustParseDate(t, "2021-01-19")).fees.Assessments; len(got) != 0 {
+		t.Errorf("calculateBalances(), want no fees, got:%v", got)
+	}
+}
+
+func TestFeeSchedule_validate(t *testing.T) {
+	tests := []struct {
+		description string
+		fees        FeeSchedule
+		wantErr     bool
+	}{
+		{"a valid schedule", FeeSchedule{LateFee: decimal.NewFromInt(25), GraceDays: 5}, false},
+		{"a negative fee", FeeSchedule{ReturnedPaymentFee: decimal.NewFromInt(-1)}, true},
+		{"a negative grace window", FeeSchedule{GraceDays: -1}, true},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v", test.description)
+		if err := test.fees.validate(); (err != nil) != test.wantErr {
+			t.Errorf("validate(), want error:%v, got:%v", test.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package accord
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	rollNone              string = "none"
+	rollFollowing         string = "following"
+	rollModifiedFollowing string = "modified_following"
+	rollPreceding         string = "preceding"
+)
+
+//  HolidayCalendar knows which days our ACH processor can settle on: weekdays that
+//  aren't listed as holidays
+type HolidayCalendar struct {
+	holidays map[CivilDate]string //  Key holiday date, value its description
+}
+
+//  RollConvention moves a due date that lands on a weekend or holiday onto a business day
+type RollConvention struct {
+	name     string
+	calendar *HolidayCalendar
+}
+
+// LoadHolidayCalendar reads a holiday file: one YYYY-MM-DD date per line, optionally followed
+// by a description. Blank lines and lines starting with # are skipped
+func LoadHolidayCalendar(path string) (*HolidayCalendar, error) {
+	var err error = nil
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rvalue := &HolidayCalendar{holidays: make(map[CivilDate]string)}
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, " ", 2)
+		date, tempErr := ParseCivilDate(fields[0])
+		if tempErr != nil {
+			return nil, fmt.Errorf("%v line %v:%v", path, lineNumber, tempErr)
+		}
+
+		description := ""
+		if len(fields) > 1 {
+			description = strings.TrimSpace(fields[1])
+		}
+		rvalue.holidays[date] = description
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rvalue, nil
+}
+
+//  isBusinessDay reports whether d is neither a weekend nor a holiday. A nil calendar
+//  only knows about weekends
+func (cal *HolidayCalendar) isBusinessDay(d CivilDate) bool {
+	weekday := d.Weekday()
+	if weekday == time.Saturday || weekday == time.Sunday {
+		return false
+	}
+	if cal != nil {
+		if _, ok := cal.holidays[d]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+//  parseRollConvention builds a RollConvention from its name and the calendar it rolls against
+func parseRollConvention(name string, calendar *HolidayCalendar) (RollConvention, error) {
+	convention := strings.ToLower(strings.TrimSpace(name))
+
+	switch convention {
+	case rollNone, rollFollowing, rollModifiedFollowing, rollPreceding:
+		return RollConvention{name: convention, calendar: calendar}, nil
+	default:
+		return RollConvention{}, fmt.Errorf("Received unexpected roll convention %v; expected none, following, modified_following or preceding", name)
+	}
+}
+
+//  adjust moves d onto a business day:
+//  following takes the next business day, preceding the previous one, and modified following
+//  takes the next business day unless that crosses into a new month, in which case it goes back instead
+func (roll RollConvention) adjust(d CivilDate) CivilDate {
+	switch roll.name {
+	case rollFollowing:
+		return roll.calendar.rollForward(d)
+	case rollPreceding:
+		return roll.calendar.rollBack(d)
+	case rollModifiedFollowing:
+		rvalue := roll.calendar.rollForward(d)
+		if rvalue.Month != d.Month {
+			rvalue = roll.calendar.rollBack(d)
+		}
+		return rvalue
+	default:
+		return d
+	}
+}
+
+//  rollForward returns the first business day on or after d
+func (cal *HolidayCalendar) rollForward(d CivilDate) CivilDate {
+	for !cal.isBusinessDay(d) {
+		d = d.addDays(1)
+	}
+	return d
+}
+
+//  rollBack returns the last business day on or before d
+func (cal *HolidayCalendar) rollBack(d CivilDate) CivilDate {
+	for !cal.isBusinessDay(d) {
+		d = d.addDays(-1)
+	}
+	return d
+}
@@ -0,0 +1,84 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func loadTestHolidayCalendar(t *testing.T) *HolidayCalendar {
+	calendar, err := LoadHolidayCalendar("../holidays/us_federal_reserve.txt")
+	if err != nil {
+		t.Fatalf("error loading holiday calendar:%v", err)
+	}
+	return calendar
+}
+
+func TestRollConvention_adjust(t *testing.T) {
+	calendar := loadTestHolidayCalendar(t)
+
+	tests := []struct {
+		description string
+		convention  string
+		date        string
+		want        string
+	}{
+		{"a business day is left alone", rollFollowing, "2021-07-07", "2021-07-07"},
+		{"following skips the weekend and the observed Independence Day", rollFollowing, "2021-07-03", "2021-07-06"},
+		{"preceding goes back to Friday", rollPreceding, "2021-07-03", "2021-07-02"},
+		{"modified following stays in the month", rollModifiedFollowing, "2021-07-31", "2021-07-30"},
+		{"modified following rolls forward inside the month", rollModifiedFollowing, "2021-07-04", "2021-07-06"},
+		{"none leaves the holiday alone", rollNone, "2021-07-05", "2021-07-05"},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v", test.description)
+		roll, err := parseRollConvention(test.convention, calendar)
+		if err != nil {
+			t.Errorf("parseRollConvention(%v) unexpected error:%v", test.convention, err)
+			continue
+		}
+		got := roll.adjust(mustParseDate(t, test.date))
+		if got.String() != test.want {
+			t.Errorf("adjust(), want:%v, got:%v", test.want, got)
+		}
+	}
+
+	t.Logf("Checking an unknown roll convention is rejected")
+	if _, err := parseRollConvention("nearest", calendar); err == nil {
+		t.Errorf("parseRollConvention() expected an error")
+	}
+}
+
+func TestRollConvention_schedule(t *testing.T) {
+	config := testSettings()
+	config.roll, _ = parseRollConvention(rollFollowing, loadTestHolidayCalendar(t))
+
+	//  A weekly plan on Saturdays rolls every due date to the following Monday,
+	//  or the Tuesday after Independence Day
+	plan := testPlan()
+	plan.AmountToPay = decimal.NewFromInt(1000)
+	plan.StartDate = "2021-06-26"
+	plan.settings = config
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(1000)}, plan, []Payment{testPayment("2021-06-28", 100)}, "2021-12-31")
+
+	t.Logf("Checking the schedule uses rolled dates")
+	for _, date := range []string{"2021-06-28", "2021-07-06", "2021-07-12"} {
+		if !debt.paymentPlan.isPaymentDateAScheduledDate(mustParseDate(t, date)) {
+			t.Errorf("generatePaymentSchedule() expected %v to be scheduled", date)
+		}
+	}
+	if debt.paymentPlan.isPaymentDateAScheduledDate(mustParseDate(t, "2021-07-03")) {
+		t.Errorf("generatePaymentSchedule() didn't expect a Saturday to be scheduled")
+	}
+
+	t.Logf("Checking a payment on the rolled date counts and the next due date is rolled")
+	if _, scheduled := debt.paymentPlan.matchScheduledDate(debt.paymentPlan.payments[0]); !scheduled {
+		t.Errorf("matchScheduledDate() expected a payment on the rolled due date to be scheduled")
+	}
+	got := debt.calculateNextPaymentDate(false)
+	want := "2021-07-06"
+	if got != want {
+		t.Errorf("calculateNextPaymentDate(), want:%v, got:%v", want, got)
+	}
+}
@@ -0,0 +1,138 @@
+package accord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	compoundingSimple string = "simple"
+	compoundingDaily  string = "daily"
+	dayCountActual365 string = "actual/365"
+	dayCount30360     string = "30/360"
+
+	//  Decimal places carried through compounding before anything is rounded to cents
+	interestPrecision int32 = 18
+)
+
+//  InterestTerms are the optional interest terms of a debt
+type InterestTerms struct {
+	APR         decimal.Decimal `json:"apr"`         //  Annual percentage rate, e.g. 12.5 for 12.5%
+	Compounding string          `json:"compounding"` //  simple, or daily to charge interest on unpaid interest
+	DayCount    string          `json:"day_count"`   //  actual/365 or 30/360
+}
+
+//  InterestBreakdown splits what has been paid and what is still owed into interest and principal
+type InterestBreakdown struct {
+	InterestAccrued      decimal.Decimal `json:"interest_accrued"`
+	InterestPaid         decimal.Decimal `json:"interest_paid"`
+	PrincipalPaid        decimal.Decimal `json:"principal_paid"`
+	InterestOutstanding  decimal.Decimal `json:"interest_outstanding"`
+	PrincipalOutstanding decimal.Decimal `json:"principal_outstanding"`
+}
+
+//  validate makes sure the terms are ones we know how to calculate
+func (terms *InterestTerms) validate() error {
+	if terms.APR.IsNegative() {
+		return fmt.Errorf("Received a negative APR of %v", terms.APR)
+	}
+
+	terms.Compounding = strings.ToLower(strings.TrimSpace(terms.Compounding))
+	if terms.Compounding == "" {
+		terms.Compounding = compoundingSimple
+	}
+	if terms.Compounding != compoundingSimple && terms.Compounding != compoundingDaily {
+		return fmt.Errorf("Received unexpected compounding %v; expected simple or daily", terms.Compounding)
+	}
+
+	terms.DayCount = strings.ToLower(strings.TrimSpace(terms.DayCount))
+	if terms.DayCount == "" {
+		terms.DayCount = dayCountActual365
+	}
+	if terms.DayCount != dayCountActual365 && terms.DayCount != dayCount30360 {
+		return fmt.Errorf("Received unexpected day count convention %v; expected actual/365 or 30/360", terms.DayCount)
+	}
+
+	return nil
+}
+
+//  dayCount returns the days between two dates and the days in a year under the terms' convention
+func (terms *InterestTerms) dayCount(from CivilDate, to CivilDate) (int, int) {
+	if terms.DayCount == dayCount30360 {
+		return days30360(from, to), 360
+	}
+	return to.daysSince(from), 365
+}
+
+//  accrue returns the interest owed on principal (and, when compounding daily, on unpaid interest)
+//  between two dates, rounded to the currency's minor unit with the rounding mode
+func (terms *InterestTerms) accrue(principal decimal.Decimal, unpaidInterest decimal.Decimal, from CivilDate, to CivilDate, currency string, rounding string) decimal.Decimal {
+	days, basis := terms.dayCount(from, to)
+	if days <= 0 || !terms.APR.IsPositive() {
+		return decimal.Zero
+	}
+
+	dailyRate := terms.APR.Div(decimal.NewFromInt(100)).DivRound(decimal.NewFromInt(int64(basis)), interestPrecision)
+
+	if terms.Compounding == compoundingDaily {
+		balance := principal.Add(unpaidInterest)
+		growth := powInt(decimal.NewFromInt(1).Add(dailyRate), days)
+		return roundAmount(balance.Mul(growth).Sub(balance), currency, rounding)
+	}
+
+	return roundAmount(principal.Mul(dailyRate).Mul(decimal.NewFromInt(int64(days))), currency, rounding)
+}
+
+//  days30360 counts days between two dates under the US 30/360 (bond basis) convention
+func days30360(from CivilDate, to CivilDate) int {
+	d1 := from.Day
+	d2 := to.Day
+	if d1 == 31 {
+		d1 = 30
+	}
+	if d2 == 31 && d1 == 30 {
+		d2 = 30
+	}
+	return 360*(to.Year-from.Year) + 30*(int(to.Month)-int(from.Month)) + d2 - d1
+}
+
+//  powInt raises base to a non-negative integer power by repeated squaring, keeping the
+//  intermediate results to interestPrecision places so they don't grow without bound
+func powInt(base decimal.Decimal, exponent int) decimal.Decimal {
+	rvalue := decimal.NewFromInt(1)
+	for exponent > 0 {
+		if exponent&1 == 1 {
+			rvalue = rvalue.Mul(base).Round(interestPrecision)
+		}
+		base = base.Mul(base).Round(interestPrecision)
+		exponent >>= 1
+	}
+	return rvalue
+}
+
+//  calculateInterest accrues interest from the plan's start date up to asOf and splits what has been
+//  paid between interest and principal following the payment waterfall, so interest always runs on
+//  what principal is actually left. Payments made before the plan starts go straight to principal.
+//  Returns nil when the debt has no interest terms or no plan to date the accrual from
+func (debt *Debt) calculateInterest(asOf CivilDate, updateObject bool) *InterestBreakdown {
+	if debt.InterestTerms == nil || debt.paymentPlan == nil {
+		return nil
+	}
+	return debt.interestFrom(debt.calculateBalances(asOf), updateObject)
+}
+
+//  interestFrom takes the interest breakdown from balances already replayed
+func (debt *Debt) interestFrom(balances *debtBalances, updateObject bool) *InterestBreakdown {
+	if debt.InterestTerms == nil || balances == nil {
+		return nil
+	}
+
+	rvalue := &balances.interest
+
+	if updateObject {
+		debt.Interest = rvalue
+	}
+	return rvalue
+}
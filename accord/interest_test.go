@@ -0,0 +1,125 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestInterestTerms_validate(t *testing.T) {
+	t.Logf("Checking the defaults")
+	terms := InterestTerms{APR: decimal.NewFromInt(5)}
+	if err := terms.validate(); err != nil || terms.Compounding != compoundingSimple || terms.DayCount != dayCountActual365 {
+		t.Errorf("validate(), want:simple actual/365, got:%v %v %v", terms.Compounding, terms.DayCount, err)
+	}
+
+	tests := []struct {
+		description string
+		terms       InterestTerms
+	}{
+		{"a negative APR", InterestTerms{APR: decimal.NewFromInt(-1)}},
+		{"an unknown compounding", InterestTerms{APR: decimal.NewFromInt(5), Compounding: "monthly"}},
+		{"an unknown day count", InterestTerms{APR: decimal.NewFromInt(5), DayCount: "actual/360"}},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v is rejected", test.description)
+		if err := test.terms.validate(); err == nil {
+			t.Errorf("validate() expected an error")
+		}
+	}
+}
+
+func TestInterestTerms_dayCount(t *testing.T) {
+	from := mustParseDate(t, "2021-01-31")
+	to := mustParseDate(t, "2021-03-01")
+
+	tests := []struct {
+		dayCount  string
+		wantDays  int
+		wantBasis int
+	}{
+		{dayCountActual365, 29, 365},
+		{dayCount30360, 31, 360},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v", test.dayCount)
+		terms := InterestTerms{DayCount: test.dayCount}
+		days, basis := terms.dayCount(from, to)
+		if days != test.wantDays || basis != test.wantBasis {
+			t.Errorf("dayCount(), want:%v/%v, got:%v/%v", test.wantDays, test.wantBasis, days, basis)
+		}
+	}
+}
+
+func TestInterestTerms_accrue(t *testing.T) {
+	from := mustParseDate(t, "2021-01-01")
+	to := mustParseDate(t, "2021-04-11")
+
+	//  36.5% over 365 days is exactly 0.1% a day
+	tests := []struct {
+		compounding string
+		want        string
+	}{
+		{compoundingSimple, "1000"},
+		{compoundingDaily, "1051.16"},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v interest", test.compounding)
+		terms := InterestTerms{APR: decimal.RequireFromString("36.5"), Compounding: test.compounding, DayCount: dayCountActual365}
+		got := terms.accrue(decimal.NewFromInt(10000), decimal.Zero, from, to, defaultCurrency, roundHalfUp)
+		if want := decimal.RequireFromString(test.want); !got.Equal(want) {
+			t.Errorf("accrue(), want:%v, got:%v", want, got)
+		}
+	}
+}
+
+func TestDebt_calculateInterest(t *testing.T) {
+	terms := InterestTerms{APR: decimal.RequireFromString("36.5")}
+
+	//  10 days at 0.1% on $1000 is $10 of interest, so the $50 payment pays $10 of interest and $40
+	//  of principal. The next 10 days run on the $960 left
+	t.Logf("Checking payments go to interest first")
+	plan := testPlan()
+	plan.AmountToPay = decimal.NewFromInt(1000)
+	plan.InstallmentAmount = decimal.Zero
+	plan.StartDate = "2021-01-01"
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(1000), InterestTerms: &terms}, plan, []Payment{testPayment("2021-01-11", 50)}, "2021-12-31")
+	got := debt.calculateInterest(mustParseDate(t, "2021-01-21"), true)
+
+	want := InterestBreakdown{
+		InterestAccrued:      decimal.RequireFromString("19.6"),
+		InterestPaid:         decimal.NewFromInt(10),
+		PrincipalPaid:        decimal.NewFromInt(40),
+		InterestOutstanding:  decimal.RequireFromString("9.6"),
+		PrincipalOutstanding: decimal.NewFromInt(960),
+	}
+	if !got.InterestAccrued.Equal(want.InterestAccrued) || !got.InterestPaid.Equal(want.InterestPaid) || !got.PrincipalPaid.Equal(want.PrincipalPaid) ||
+		!got.InterestOutstanding.Equal(want.InterestOutstanding) || !got.PrincipalOutstanding.Equal(want.PrincipalOutstanding) {
+		t.Errorf("calculateInterest(), want:%+v, got:%+v", want, *got)
+	}
+	if debt.Interest != got {
+		t.Errorf("calculateInterest(true) didn't update the debt")
+	}
+
+	t.Logf("Checking the remaining amount includes unpaid interest")
+	debt.asOf = mustParseDate(t, "2021-01-21")
+	if remaining := remainingAmount(t, &debt); !remaining.Equal(decimal.RequireFromString("969.6")) {
+		t.Errorf("calculateRemainingAmount(), want:969.6, got:%v", remaining)
+	}
+
+	t.Logf("Checking interest stops once the debt is paid off")
+	debt = makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(1000), InterestTerms: &terms}, plan, []Payment{testPayment("2021-01-11", 1010)}, "2021-12-31")
+	got = debt.calculateInterest(mustParseDate(t, "2021-06-01"), false)
+	if !got.InterestAccrued.Equal(decimal.NewFromInt(10)) || !got.PrincipalOutstanding.IsZero() {
+		t.Errorf("calculateInterest(), want 10 of interest and nothing outstanding, got:%+v", *got)
+	}
+
+	t.Logf("Checking a debt without terms accrues nothing")
+	debt.InterestTerms = nil
+	if got = debt.calculateInterest(mustParseDate(t, "2021-06-01"), false); got != nil {
+		t.Errorf("calculateInterest(), want:nil, got:%+v", *got)
+	}
+}
@@ -0,0 +1,317 @@
+package accord
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+//  Ledger accounts. The debtor receivable is debit normal: it goes up with what the debtor owes
+//  and down with what they pay
+const (
+	accountReceivable         string = "debtor_receivable"
+	accountCash               string = "cash"
+	accountPlacements         string = "placements" //  Where debts placed with us come from
+	accountSettlementDiscount string = "settlement_discount"
+	accountFeeIncome          string = "fee_income"
+	accountInterestIncome     string = "interest_income"
+)
+
+//  JournalLine is one side of a journal entry
+type JournalLine struct {
+	Account string          `json:"account"`
+	Debit   decimal.Decimal `json:"debit"`
+	Credit  decimal.Decimal `json:"credit"`
+}
+
+//  JournalEntry is a balanced set of debits and credits for a single debt transaction
+type JournalEntry struct {
+	DebtID      int           `json:"debt_id"`
+	Date        CivilDate     `json:"date"`
+	Currency    string        `json:"currency"`
+	Description string        `json:"description"`
+	Lines       []JournalLine `json:"lines"`
+}
+
+//  TrialBalanceLine is the total activity and balance of an account in a currency
+type TrialBalanceLine struct {
+	Account  string          `json:"account"`
+	Currency string          `json:"currency"`
+	Debits   decimal.Decimal `json:"debits"`
+	Credits  decimal.Decimal `json:"credits"`
+	Balance  decimal.Decimal `json:"balance"` //  Debits less credits
+}
+
+//  TrialBalance lists every account's balance. It is balanced when debits equal credits in every currency
+//  and every debt's receivable matches what the debt reports it owes
+type TrialBalance struct {
+	Lines        []TrialBalanceLine `json:"lines"`
+	Balanced     bool               `json:"balanced"`
+	Unreconciled []int              `json:"unreconciled"` //  Debts whose receivable isn't remaining_amount less credit_balance
+}
+
+//  Ledger is the journal of every debt transaction and the trial balance it produces
+type Ledger struct {
+	AsOf         CivilDate      `json:"as_of"`
+	Journal      []JournalEntry `json:"journal"`
+	TrialBalance TrialBalance   `json:"trial_balance"`
+}
+
+//  StatementLine is a transaction on a debtor's receivable and the balance after it
+type StatementLine struct {
+	Date        CivilDate       `json:"date"`
+	Description string          `json:"description"`
+	Debit       decimal.Decimal `json:"debit"`
+	Credit      decimal.Decimal `json:"credit"`
+	Balance     decimal.Decimal `json:"balance"`
+}
+
+//  AccountStatement is the history of a single debt's receivable
+type AccountStatement struct {
+	DebtID         int             `json:"debt_id"`
+	Currency       string          `json:"currency"`
+	Lines          []StatementLine `json:"lines"`
+	ClosingBalance decimal.Decimal `json:"closing_balance"` //  Matches remaining_amount less credit_balance
+}
+
+//  newJournalEntry makes a two line entry debiting one account and crediting another
+func (debt *Debt) newJournalEntry(date CivilDate, description string, debitAccount string, creditAccount string, amount decimal.Decimal) JournalEntry {
+	return JournalEntry{
+		DebtID:      debt.ID,
+		Date:        date,
+		Currency:    debt.currency(),
+		Description: description,
+		Lines: []JournalLine{
+			{Account: debitAccount, Debit: amount, Credit: decimal.Zero},
+			{Account: creditAccount, Debit: decimal.Zero, Credit: amount},
+		},
+	}
+}
+
+//  isBalanced is true when the entry's debits equal its credits
+func (entry *JournalEntry) isBalanced() bool {
+	total := decimal.Zero
+	for _, line := range entry.Lines {
+		total = total.Add(line.Debit).Sub(line.Credit)
+	}
+	return total.IsZero()
+}
+
+//  journalEntries turns the debt's history up to asOf into journal entries, in date order:
+//  -  the debt being placed, on the plan's start date. A debt without a plan has no date it was placed
+//     on, so it has no entries at all
+//  -  any settlement discount the plan gives, on the same day
+//  -  fees as they're assessed and interest as it accrues
+//  -  money received, and money that goes back out through reversals, chargebacks and refunds.
+//     Pending and failed payments never move money, so they have no entries
+func (debt *Debt) journalEntries(asOf CivilDate) []JournalEntry {
+	var rvalue []JournalEntry
+
+	add := func(date CivilDate, description string, debitAccount string, creditAccount string, amount decimal.Decimal) {
+		if !amount.IsZero() {
+			rvalue = append(rvalue, debt.newJournalEntry(date, description, debitAccount, creditAccount, amount))
+		}
+	}
+
+	plan := debt.paymentPlan
+	if plan == nil {
+		return rvalue
+	}
+
+	add(plan.startDate, "Debt placed", accountReceivable, accountPlacements, debt.Amount)
+	add(plan.startDate, "Settlement discount", accountSettlementDiscount, accountReceivable, debt.Amount.Sub(debt.amountToPay()))
+
+	balances := debt.calculateBalances(asOf)
+	for _, fee := range balances.fees.Assessments {
+		add(fee.Date, fmt.Sprintf("Fee: %v", fee.Type), accountReceivable, accountFeeIncome, fee.Amount)
+	}
+	for _, accrual := range balances.accruals {
+		add(accrual.date, "Interest accrued", accountReceivable, accountInterestIncome, accrual.amount)
+	}
+
+	received := make(map[int]Payment)
+	for _, pmt := range plan.transactions {
+		if pmt.paymentType() == paymentTypePayment && pmt.ID != 0 {
+			received[pmt.ID] = pmt
+		}
+	}
+
+	reversed := make(map[int]bool)
+	refunded := make(map[int]decimal.Decimal)
+
+	for _, pmt := range plan.transactions {
+		switch pmt.paymentType() {
+		case paymentTypePayment:
+			switch pmt.status() {
+			case paymentPending, paymentFailed:
+				continue
+			}
+			add(pmt.date, "Payment received", accountCash, accountReceivable, pmt.Amount)
+
+			//  A payment marked reversed or refunded without a record of when went back the same day
+			switch pmt.status() {
+			case paymentReversed:
+				add(pmt.date, "Payment reversed", accountReceivable, accountCash, pmt.Amount)
+			case paymentRefunded:
+				add(pmt.date, "Payment refunded", accountReceivable, accountCash, pmt.Amount)
+			}
+		default:
+			if pmt.status() != paymentSettled || pmt.OriginalPaymentID == nil {
+				continue
+			}
+			original, ok := received[*pmt.OriginalPaymentID]
+			if !ok || original.status() != paymentSettled {
+				continue
+			}
+			if reversed[original.ID] {
+				continue
+			}
+
+			//  A reversal takes back whatever is left of the payment, whatever amount it says.
+			//  A refund can't give back more than that either
+			amount := original.Amount.Sub(refunded[original.ID])
+			if pmt.paymentType() == paymentTypeRefund {
+				amount = decimal.Min(pmt.Amount, amount)
+				refunded[original.ID] = refunded[original.ID].Add(amount)
+			} else {
+				reversed[original.ID] = true
+			}
+			add(pmt.date, fmt.Sprintf("Payment %v", pmt.paymentType()), accountReceivable, accountCash, amount)
+		}
+	}
+
+	sort.SliceStable(rvalue, func(i, j int) bool { return rvalue[i].Date.Before(rvalue[j].Date) })
+	return rvalue
+}
+
+//  accountStatement lists the transactions on the debt's receivable with a running balance
+func (debt *Debt) accountStatement(asOf CivilDate) AccountStatement {
+	return debt.statementFrom(debt.journalEntries(asOf))
+}
+
+//  statementFrom runs the debt's journal entries through its receivable
+func (debt *Debt) statementFrom(entries []JournalEntry) AccountStatement {
+	rvalue := AccountStatement{DebtID: debt.ID, Currency: debt.currency(), Lines: make([]StatementLine, 0), ClosingBalance: decimal.Zero}
+
+	for _, entry := range entries {
+		for _, line := range entry.Lines {
+			if line.Account != accountReceivable {
+				continue
+			}
+			rvalue.ClosingBalance = rvalue.ClosingBalance.Add(line.Debit).Sub(line.Credit)
+			rvalue.Lines = append(rvalue.Lines, StatementLine{
+				Date:        entry.Date,
+				Description: entry.Description,
+				Debit:       line.Debit,
+				Credit:      line.Credit,
+				Balance:     rvalue.ClosingBalance,
+			})
+		}
+	}
+	return rvalue
+}
+
+//  buildTrialBalance totals the journal by account and currency
+func buildTrialBalance(journal []JournalEntry) TrialBalance {
+	type accountKey struct {
+		account  string
+		currency string
+	}
+
+	totals := make(map[accountKey]*TrialBalanceLine)
+	net := make(map[string]decimal.Decimal)
+
+	for _, entry := range journal {
+		for _, line := range entry.Lines {
+			key := accountKey{line.Account, entry.Currency}
+			total, ok := totals[key]
+			if !ok {
+				total = &TrialBalanceLine{Account: line.Account, Currency: entry.Currency, Debits: decimal.Zero, Credits: decimal.Zero, Balance: decimal.Zero}
+				totals[key] = total
+			}
+			total.Debits = total.Debits.Add(line.Debit)
+			total.Credits = total.Credits.Add(line.Credit)
+			total.Balance = total.Debits.Sub(total.Credits)
+			net[entry.Currency] = net[entry.Currency].Add(line.Debit).Sub(line.Credit)
+		}
+	}
+
+	rvalue := TrialBalance{Lines: make([]TrialBalanceLine, 0, len(totals)), Balanced: true, Unreconciled: make([]int, 0)}
+	for _, entry := range journal {
+		if !entry.isBalanced() {
+			rvalue.Balanced = false
+		}
+	}
+	for _, total := range totals {
+		rvalue.Lines = append(rvalue.Lines, *total)
+	}
+	sort.Slice(rvalue.Lines, func(i, j int) bool {
+		if rvalue.Lines[i].Currency != rvalue.Lines[j].Currency {
+			return rvalue.Lines[i].Currency < rvalue.Lines[j].Currency
+		}
+		return rvalue.Lines[i].Account < rvalue.Lines[j].Account
+	})
+
+	for _, amount := range net {
+		if !amount.IsZero() {
+			rvalue.Balanced = false
+		}
+	}
+	return rvalue
+}
+
+//  buildLedger journals every debt's transactions up to asOf and checks the trial balance. Double entry
+//  keeps debits and credits equal whatever the journal says, so each debt's receivable is also checked
+//  against the remaining_amount and credit_balance it reports. A debt that doesn't match leaves the trial
+//  balance unbalanced
+func buildLedger(debts map[int]Debt, asOf CivilDate) Ledger {
+	rvalue := Ledger{AsOf: asOf, Journal: make([]JournalEntry, 0)}
+	var unreconciled []int
+
+	for _, id := range sortedDebtIDs(debts) {
+		debt := debts[id]
+		if debt.paymentPlan == nil {
+			continue
+		}
+		entries := debt.journalEntries(asOf)
+		statement := debt.statementFrom(entries)
+		if !statement.ClosingBalance.Equal(debt.RemainingAmount.Sub(debt.CreditBalance)) {
+			unreconciled = append(unreconciled, debt.ID)
+		}
+		rvalue.Journal = append(rvalue.Journal, entries...)
+	}
+	sort.SliceStable(rvalue.Journal, func(i, j int) bool { return rvalue.Journal[i].Date.Before(rvalue.Journal[j].Date) })
+
+	rvalue.TrialBalance = buildTrialBalance(rvalue.Journal)
+	if len(unreconciled) > 0 {
+		rvalue.TrialBalance.Unreconciled = unreconciled
+		rvalue.TrialBalance.Balanced = false
+	}
+	return rvalue
+}
+
+//  buildStatements makes an account statement for every debt with a plan, in debt id order. Debts without
+//  a plan aren't in the ledger, so they have no statement
+func buildStatements(debts map[int]Debt, asOf CivilDate) []AccountStatement {
+	rvalue := make([]AccountStatement, 0, len(debts))
+
+	for _, id := range sortedDebtIDs(debts) {
+		debt := debts[id]
+		if debt.paymentPlan == nil {
+			continue
+		}
+		rvalue = append(rvalue, debt.accountStatement(asOf))
+	}
+	return rvalue
+}
+
+//  sortedDebtIDs returns the ids of the debts in order, so reports come out the same way every run
+func sortedDebtIDs(debts map[int]Debt) []int {
+	rvalue := make([]int, 0, len(debts))
+	for id := range debts {
+		rvalue = append(rvalue, id)
+	}
+	sort.Ints(rvalue)
+	return rvalue
+}
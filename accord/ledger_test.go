@@ -0,0 +1,110 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBuildLedger_reconciles(t *testing.T) {
+	var debts map[int]Debt
+
+	err := makeMockGraph(&debts)
+	if err != nil {
+		t.Fatalf("buildLedger(), error making mock data: %v", err)
+	}
+
+	asOf := testSettings().evaluationDate()
+
+	t.Logf("Checking the trial balance balances")
+	ledger := buildLedger(debts, asOf)
+	if !ledger.TrialBalance.Balanced || len(ledger.TrialBalance.Unreconciled) != 0 {
+		t.Errorf("buildLedger(), want a balanced trial balance, got:%v", ledger.TrialBalance)
+	}
+
+	t.Logf("Checking debts without a plan aren't journalled")
+	for _, entry := range ledger.Journal {
+		if debt := debts[entry.DebtID]; debt.paymentPlan == nil {
+			t.Errorf("buildLedger(), want:no entries for debt %v without a plan, got:%v", debt.ID, entry)
+		}
+	}
+
+	t.Logf("Checking each debt's receivable matches what it owes")
+	for _, statement := range buildStatements(debts, asOf) {
+		debt := debts[statement.DebtID]
+		if want := remainingAmount(t, &debt).Sub(debt.CreditBalance); !statement.ClosingBalance.Equal(want) {
+			t.Errorf("accountStatement() debt %v, want:%v, got:%v", debt.ID, want, statement.ClosingBalance)
+		}
+	}
+}
+
+func TestBuildLedger_unreconciled(t *testing.T) {
+	var debts map[int]Debt
+
+	err := makeMockGraph(&debts)
+	if err != nil {
+		t.Fatalf("buildLedger(), error making mock data: %v", err)
+	}
+
+	//  The journal still balances, but debt 4 no longer reports what its receivable says it owes
+	debt := debts[4]
+	debt.RemainingAmount = debt.RemainingAmount.Add(decimal.NewFromInt(1))
+	debts[4] = debt
+
+	t.Logf("Checking a debt that doesn't match its receivable unbalances the trial balance")
+	ledger := buildLedger(debts, testSettings().evaluationDate())
+	if ledger.TrialBalance.Balanced {
+		t.Errorf("buildLedger(), want:unbalanced, got:%v", ledger.TrialBalance)
+	}
+	if len(ledger.TrialBalance.Unreconciled) != 1 || ledger.TrialBalance.Unreconciled[0] != 4 {
+		t.Errorf("buildLedger(), want:[4], got:%v", ledger.TrialBalance.Unreconciled)
+	}
+}
+
+func TestDebt_journalEntries(t *testing.T) {
+	original := 2
+	payments := []Payment{
+		{ID: 1, Amount: decimal.NewFromInt(100), Date: "2021-01-04"},
+		{ID: 2, Amount: decimal.NewFromInt(100), Date: "2021-01-11"},
+		{ID: 3, Amount: decimal.NewFromInt(50), Date: "2021-01-12", Status: paymentFailed},
+		{ID: 4, Amount: decimal.NewFromInt(100), Date: "2021-01-13", Type: paymentTypeReversal, OriginalPaymentID: &original},
+	}
+
+	//  A $500 debt settled for $400 with a $30 returned payment fee
+	plan := testPlan()
+	plan.FeeSchedule = &FeeSchedule{ReturnedPaymentFee: decimal.NewFromInt(30)}
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(500)}, plan, payments, "2021-01-14")
+
+	asOf := mustParseDate(t, "2021-01-14")
+	entries := debt.journalEntries(asOf)
+
+	want := []struct {
+		description string
+		amount      int64
+	}{
+		{"Debt placed", 500},
+		{"Settlement discount", 100},
+		{"Payment received", 100},
+		{"Payment received", 100},
+		{"Fee: returned_payment", 30},
+		{"Fee: returned_payment", 30},
+		{"Payment reversal", 100},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("journalEntries(), want:%v entries, got:%v", len(want), entries)
+	}
+	for idx := range want {
+		if !entries[idx].isBalanced() {
+			t.Errorf("journalEntries() entry %v isn't balanced: %v", idx, entries[idx])
+		}
+		if entries[idx].Description != want[idx].description || !entries[idx].Lines[0].Debit.Equal(decimal.NewFromInt(want[idx].amount)) {
+			t.Errorf("journalEntries() entry %v, want:%v of %v, got:%v", idx, want[idx].description, want[idx].amount, entries[idx])
+		}
+	}
+
+	t.Logf("Checking the statement closes on what's owed")
+	statement := debt.accountStatement(asOf)
+	if want := debt.remainingAmountAsOf(asOf); !statement.ClosingBalance.Equal(want) || !want.Equal(decimal.NewFromInt(360)) {
+		t.Errorf("accountStatement(), want:360, got:%v (remaining %v)", statement.ClosingBalance, want)
+	}
+}
@@ -0,0 +1,94 @@
+package accord
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	matchExact  string = "exact"
+	matchWithin string = "within"
+	matchLate   string = "late"
+)
+
+//  MatchPolicy decides whether a payment was made on schedule, i.e. close enough to a due date
+//  to count as that installment's payment. The spec only recognizes payments made exactly on the
+//  scheduled date, which is still the default, but real debtors pay a day or two either side and
+//  a grace window is often wanted. An installment paid inside the window is on time, so it isn't
+//  missed, doesn't make the plan delinquent and isn't charged a late fee. How much of the schedule
+//  a payment covers is still up to allocatePayments.
+type MatchPolicy struct {
+	kind string //  exact, within (± days) or late (up to days after only)
+	days int
+}
+
+//  parseMatchPolicy reads a policy written as exact, within:N or late:N
+func parseMatchPolicy(value string) (MatchPolicy, error) {
+	var rvalue MatchPolicy
+
+	policy := strings.ToLower(strings.TrimSpace(value))
+	if policy == matchExact {
+		rvalue.kind = matchExact
+		return rvalue, nil
+	}
+
+	parts := strings.Split(policy, ":")
+	if len(parts) != 2 || (parts[0] != matchWithin && parts[0] != matchLate) {
+		return rvalue, fmt.Errorf("Received unexpected payment match policy %v; expected exact, within:N or late:N", value)
+	}
+
+	days, err := strconv.Atoi(parts[1])
+	if err != nil || days < 0 {
+		return rvalue, fmt.Errorf("Received unexpected number of days in payment match policy %v", value)
+	}
+
+	rvalue.kind = parts[0]
+	rvalue.days = days
+	return rvalue, nil
+}
+
+//  String formats the policy the same way parseMatchPolicy reads it
+func (policy MatchPolicy) String() string {
+	if policy.kind == matchExact || policy.kind == "" {
+		return matchExact
+	}
+	return fmt.Sprintf("%v:%v", policy.kind, policy.days)
+}
+
+//  lateDays is how many days after a due date a payment still counts as on time. Paying early is
+//  always on time, since payments are applied to the oldest installment first
+func (policy MatchPolicy) lateDays() int {
+	if policy.kind == matchExact {
+		return 0
+	}
+	return policy.days
+}
+
+//  matchScheduledDate finds the scheduled date a payment made on paymentDate counts toward.
+//  An exact match always wins; after that the closest date is preferred, and where a payment
+//  sits the same distance from two dates it goes to the earlier (a late payment) one
+func (policy MatchPolicy) matchScheduledDate(paymentDate CivilDate, isScheduled func(CivilDate) bool) (CivilDate, bool) {
+	if isScheduled(paymentDate) {
+		return paymentDate, true
+	}
+
+	if policy.kind == matchExact {
+		return CivilDate{}, false
+	}
+
+	for offset := 1; offset <= policy.days; offset++ {
+		//  Paid late: the scheduled date came before the payment
+		if candidate := paymentDate.addDays(-offset); isScheduled(candidate) {
+			return candidate, true
+		}
+		//  Paid early: only allowed when the window runs both ways
+		if policy.kind == matchWithin {
+			if candidate := paymentDate.addDays(offset); isScheduled(candidate) {
+				return candidate, true
+			}
+		}
+	}
+
+	return CivilDate{}, false
+}
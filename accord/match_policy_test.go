@@ -0,0 +1,132 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPaymentPlan_matchScheduledDate(t *testing.T) {
+	//  A single payment made on the payment date towards a plan falling due on Mondays from 2021-01-04
+	plan := testPlan()
+	plan.AmountToPay = decimal.NewFromInt(1000)
+
+	tests := []struct {
+		description   string
+		policy        string
+		paymentDate   string
+		wantScheduled bool
+		wantFor       string
+	}{
+		{"exact, paid on the day", "exact", "2021-01-11", true, "2021-01-11"},
+		{"exact, paid a day early", "exact", "2021-01-10", false, ""},
+		{"exact, paid a day late", "exact", "2021-01-12", false, ""},
+		{"within:2, paid a day early", "within:2", "2021-01-10", true, "2021-01-11"},
+		{"within:2, paid a day late", "within:2", "2021-01-12", true, "2021-01-11"},
+		{"within:2, paid outside the window", "within:2", "2021-01-14", false, ""},
+		{"late:2, paid a day early", "late:2", "2021-01-10", false, ""},
+		{"late:2, paid a day late", "late:2", "2021-01-12", true, "2021-01-11"},
+		{"late:2, paid outside the window", "late:2", "2021-01-14", false, ""},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v", test.description)
+		plan.MatchPolicy = test.policy
+		debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(1000)}, plan, []Payment{testPayment(test.paymentDate, 100)}, "2021-12-31")
+
+		scheduledFor, scheduled := debt.paymentPlan.matchScheduledDate(debt.paymentPlan.payments[0])
+		if scheduled != test.wantScheduled {
+			t.Errorf("matchScheduledDate(), want scheduled:%v, got:%v", test.wantScheduled, scheduled)
+		}
+		if scheduled && scheduledFor.String() != test.wantFor {
+			t.Errorf("matchScheduledDate() scheduled date, want:%v, got:%v", test.wantFor, scheduledFor)
+		}
+
+		//  However the payment was timed, it pays the first installment
+		next := debt.calculateNextPaymentDate(false)
+		if next != "2021-01-11" {
+			t.Errorf("calculateNextPaymentDate(), want:2021-01-11, got:%v", next)
+		}
+	}
+}
+
+func TestMatchPolicy_planOverridesRun(t *testing.T) {
+	t.Logf("Checking a plan without a policy uses the run's policy")
+	config := testSettings()
+	config.matchPolicy, _ = parseMatchPolicy("late:3")
+	plan := testPlan()
+	plan.settings = config
+	payments := []Payment{testPayment("2021-01-13", 100)}
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, plan, payments, "2021-12-31")
+	if _, scheduled := debt.paymentPlan.matchScheduledDate(debt.paymentPlan.payments[0]); !scheduled {
+		t.Errorf("matchScheduledDate() expected the run's late:3 policy to match a payment two days late")
+	}
+
+	t.Logf("Checking a plan's own policy wins over the run's")
+	plan.MatchPolicy = "exact"
+	debt = makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, plan, payments, "2021-12-31")
+	if _, scheduled := debt.paymentPlan.matchScheduledDate(debt.paymentPlan.payments[0]); scheduled {
+		t.Errorf("matchScheduledDate() expected the plan's exact policy to reject a payment two days late")
+	}
+}
+
+func TestMatchPolicy_onTime(t *testing.T) {
+	//  The first installment is paid on the day and the second, due 2021-01-11, when the test says.
+	//  Late fees have no grace days, so the window alone decides whether a payment is on time
+	tests := []struct {
+		description    string
+		policy         string
+		paymentDate    string
+		wantFees       int
+		wantDelinquent bool
+		wantMissed     int
+	}{
+		{"exact, paid a day early", "exact", "2021-01-10", 0, false, 0},
+		{"exact, paid a day late", "exact", "2021-01-12", 1, true, 1},
+		{"within:2, paid a day early", "within:2", "2021-01-10", 0, false, 0},
+		{"within:2, paid a day late", "within:2", "2021-01-12", 0, false, 0},
+		{"within:2, paid outside the window", "within:2", "2021-01-14", 1, true, 1},
+		{"late:2, paid a day early", "late:2", "2021-01-10", 0, false, 0},
+		{"late:2, paid a day late", "late:2", "2021-01-12", 0, false, 0},
+		{"late:2, paid outside the window", "late:2", "2021-01-14", 1, true, 1},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v", test.description)
+		plan := testPlan()
+		plan.MatchPolicy = test.policy
+		plan.FeeSchedule = &FeeSchedule{LateFee: decimal.NewFromInt(25)}
+		debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, plan, []Payment{testPayment("2021-01-04", 100), testPayment(test.paymentDate, 100)}, "2021-01-17")
+
+		if got := len(debt.Fees.Assessments); got != test.wantFees {
+			t.Errorf("calculateFees() late fees, want:%v, got:%v", test.wantFees, got)
+		}
+
+		delinquent := false
+		for _, transition := range debt.PlanStatusHistory {
+			delinquent = delinquent || transition.Status == statusDelinquent
+		}
+		if delinquent != test.wantDelinquent {
+			t.Errorf("calculatePlanStatus() delinquent, want:%v, got:%v", test.wantDelinquent, debt.PlanStatusHistory)
+		}
+
+		if got := debt.calculateDelinquency(mustParseDate(t, "2021-01-14"), false); got.MissedInstallments != test.wantMissed {
+			t.Errorf("calculateDelinquency() missed installments, want:%v, got:%v", test.wantMissed, got.MissedInstallments)
+		}
+	}
+}
+
+func TestParseMatchPolicy(t *testing.T) {
+	for _, value := range []string{"exact", "Within:3", "late:0"} {
+		t.Logf("Checking that %q parses", value)
+		if _, err := parseMatchPolicy(value); err != nil {
+			t.Errorf("parseMatchPolicy(%q) unexpected error:%v", value, err)
+		}
+	}
+	for _, value := range []string{"", "within", "late:-1", "early:2", "within:x"} {
+		t.Logf("Checking that %q is rejected", value)
+		if _, err := parseMatchPolicy(value); err == nil {
+			t.Errorf("parseMatchPolicy(%q) expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package accord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	//  Amounts that don't say what currency they're in are in dollars
+	defaultCurrency string = "USD"
+
+	roundHalfUp   string = "half_up"   //  Halves round away from zero
+	roundHalfEven string = "half_even" //  Banker's rounding: halves round to the even digit
+)
+
+var (
+	//  currencyMinorUnits is how many decimal places each currency is counted in, for the
+	//  currencies that don't use the usual 2
+	currencyMinorUnits map[string]int32 = map[string]int32{
+		"BHD": 3,
+		"CLP": 0,
+		"IQD": 3,
+		"ISK": 0,
+		"JOD": 3,
+		"JPY": 0,
+		"KRW": 0,
+		"KWD": 3,
+		"LYD": 3,
+		"OMR": 3,
+		"TND": 3,
+		"VND": 0,
+	}
+)
+
+//  Money is an amount in a particular currency
+type Money struct {
+	Amount   decimal.Decimal
+	Currency string
+}
+
+//  newMoney makes an amount of money, treating a blank currency as the default currency
+func newMoney(amount decimal.Decimal, currency string) Money {
+	return Money{Amount: amount, Currency: currencyOrDefault(currency)}
+}
+
+//  currencyOrDefault fills in the default currency for amounts that don't say
+func currencyOrDefault(currency string) string {
+	if len(currency) == 0 {
+		return defaultCurrency
+	}
+	return currency
+}
+
+//  add adds two amounts of money. It refuses to add amounts in different currencies
+func (money Money) add(other Money) (Money, error) {
+	if money.Currency != other.Currency {
+		return money, fmt.Errorf("Can't add %v to %v", other, money)
+	}
+	return Money{Amount: money.Amount.Add(other.Amount), Currency: money.Currency}, nil
+}
+
+//  sub takes one amount of money from another. It refuses to subtract amounts in different currencies
+func (money Money) sub(other Money) (Money, error) {
+	if money.Currency != other.Currency {
+		return money, fmt.Errorf("Can't subtract %v from %v", other, money)
+	}
+	return Money{Amount: money.Amount.Sub(other.Amount), Currency: money.Currency}, nil
+}
+
+//  round rounds the amount to the currency's minor unit
+func (money Money) round(rounding string) Money {
+	return Money{Amount: roundAmount(money.Amount, money.Currency, rounding), Currency: money.Currency}
+}
+
+func (money Money) String() string {
+	return fmt.Sprintf("%v %v", money.Amount, money.Currency)
+}
+
+//  minorUnits returns the decimal places a currency is counted in
+func minorUnits(currency string) int32 {
+	if places, ok := currencyMinorUnits[currency]; ok {
+		return places
+	}
+	return 2
+}
+
+//  addAmount adds amount to a running total. Either being zero takes the other as it is, which spares
+//  rescaling one to the other's number of decimal places
+func addAmount(total decimal.Decimal, amount decimal.Decimal) decimal.Decimal {
+	if total.IsZero() {
+		return amount
+	}
+	if amount.IsZero() {
+		return total
+	}
+	return total.Add(amount)
+}
+
+//  roundAmount rounds an amount in a currency to the currency's minor unit. The rounding mode
+//  decides which way amounts exactly half way between two minor units go
+func roundAmount(amount decimal.Decimal, currency string, rounding string) decimal.Decimal {
+	places := minorUnits(currency)
+
+	//  An amount with no more decimal places than the currency has is already rounded
+	if amount.Exponent() >= -places {
+		return amount
+	}
+	if rounding == roundHalfEven {
+		return amount.RoundBank(places)
+	}
+	return amount.Round(places)
+}
+
+//  normalizeCurrency checks a currency is an ISO 4217 style three letter code, defaulting a blank one
+func normalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) == 0 {
+		return defaultCurrency, nil
+	}
+	if len(currency) != 3 || strings.Trim(currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("Received unexpected currency %v; expected a three letter code such as USD", currency)
+	}
+	return currency, nil
+}
+
+//  parseRoundingMode reads a rounding mode: half_up, or half_even (banker's rounding)
+func parseRoundingMode(value string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case roundHalfUp, roundHalfEven:
+		return mode, nil
+	case "bankers":
+		return roundHalfEven, nil
+	default:
+		return "", fmt.Errorf("Received unexpected rounding mode %v; expected half_up or half_even", value)
+	}
+}
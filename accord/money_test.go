@@ -0,0 +1,110 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMoney_add(t *testing.T) {
+	t.Logf("Checking amounts in the same currency add up")
+	got, err := newMoney(decimal.RequireFromString("10.25"), "").add(newMoney(decimal.RequireFromString("4.75"), "USD"))
+	if err != nil || !got.Amount.Equal(decimal.NewFromInt(15)) || got.Currency != "USD" {
+		t.Errorf("add(), want:15 USD, got:%v %v", got, err)
+	}
+
+	t.Logf("Checking amounts in different currencies are refused")
+	if _, err = newMoney(decimal.NewFromInt(10), "USD").add(newMoney(decimal.NewFromInt(10), "EUR")); err == nil {
+		t.Errorf("add() expected an error")
+	}
+	if _, err = newMoney(decimal.NewFromInt(10), "USD").sub(newMoney(decimal.NewFromInt(10), "JPY")); err == nil {
+		t.Errorf("sub() expected an error")
+	}
+}
+
+func TestRoundAmount(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		mode     string
+		want     string
+	}{
+		{"2.345", "USD", roundHalfUp, "2.35"},
+		{"2.345", "USD", roundHalfEven, "2.34"},
+		{"1234.5", "JPY", roundHalfUp, "1235"},
+		{"1234.5", "JPY", roundHalfEven, "1234"},
+		{"1.2345", "BHD", roundHalfUp, "1.235"},
+		{"1.2345", "BHD", roundHalfEven, "1.234"},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v %v rounded %v", test.amount, test.currency, test.mode)
+		got := newMoney(decimal.RequireFromString(test.amount), test.currency).round(test.mode)
+		if want := decimal.RequireFromString(test.want); !got.Amount.Equal(want) {
+			t.Errorf("round(), want:%v, got:%v", want, got.Amount)
+		}
+	}
+}
+
+func TestNormalizeCurrency(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", defaultCurrency, false},
+		{" jpy", "JPY", false},
+		{"US", "", true},
+		{"U$D", "", true},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %q", test.value)
+		got, err := normalizeCurrency(test.value)
+		if got != test.want || (err != nil) != test.wantErr {
+			t.Errorf("normalizeCurrency(%q), want:%v, got:%v %v", test.value, test.want, got, err)
+		}
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	if got, err := parseRoundingMode("bankers"); err != nil || got != roundHalfEven {
+		t.Errorf("parseRoundingMode(), want:%v, got:%v %v", roundHalfEven, got, err)
+	}
+	if _, err := parseRoundingMode("down"); err == nil {
+		t.Errorf("parseRoundingMode() expected an error")
+	}
+}
+
+func TestNormalizeData_currencyMismatch(t *testing.T) {
+	debts, plans, payments := getRawTestObjects()
+
+	t.Logf("Checking a payment in a different currency is refused")
+	payments = append(payments, Payment{PaymentPlanID: 3, Amount: decimal.NewFromInt(25), Currency: "EUR", Date: "2020-10-21", date: CivilDate{2020, 10, 21}})
+	if _, err := normalizeData(debts, plans, payments, defaultSettings); err == nil {
+		t.Errorf("normalizeData() expected an error")
+	}
+
+	t.Logf("Checking a plan in a different currency from its debt is refused")
+	debts, plans, payments = getRawTestObjects()
+	plan := plans[3]
+	plan.Currency = "JPY"
+	plans[3] = plan
+	if _, err := normalizeData(debts, plans, payments, defaultSettings); err == nil {
+		t.Errorf("normalizeData() expected an error")
+	}
+}
+
+func TestDebt_evaluate_currencyMismatch(t *testing.T) {
+	t.Logf("Checking payments that can't be totalled fail the debt rather than being printed")
+	plan := PaymentPlan{ID: 1, DebtID: 1, AmountToPay: decimal.NewFromInt(300), InstallmentFrequency: "weekly", InstallmentAmount: decimal.NewFromInt(100), startDate: CivilDate{2021, 1, 4}}
+	plan.transactions = []Payment{{PaymentPlanID: 1, Amount: decimal.NewFromInt(100), Currency: "EUR", Date: "2021-01-04", date: CivilDate{2021, 1, 4}}}
+	debt := Debt{ID: 1, Amount: decimal.NewFromInt(300), paymentPlan: &plan}
+
+	if err := debt.evaluate(CivilDate{2021, 2, 1}); err == nil {
+		t.Errorf("evaluate(), want:an error for a payment in EUR, got:none")
+	}
+	if _, err := debt.calculateRemainingAmount(false); err == nil {
+		t.Errorf("calculateRemainingAmount(), want:an error for a payment in EUR, got:none")
+	}
+}
@@ -0,0 +1,164 @@
+package accord
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+//  Options are the settings a portfolio is computed with. Blank strings, nil pointers, a zero AsOf,
+//  zero StatusThresholds and zero Workers take the defaults DefaultOptions gives
+type Options struct {
+	Location         *time.Location   //  Business time zone timestamped payments are dated in
+	MatchPolicy      string           //  How late a payment can be and still be on time: exact, within:N or late:N
+	Holidays         *HolidayCalendar //  Bank holidays due dates can't fall on
+	Roll             string           //  How due dates on weekends and holidays move: none, following, modified_following or preceding
+	AsOf             CivilDate        //  Evaluate as of the end of this date instead of today
+	Clock            Clock            //  Where today comes from
+	StatusThresholds StatusThresholds //  When plans are delinquent and broken
+	Waterfall        string           //  Comma separated order payments pay off fees, interest and principal
+	Rounding         string           //  half_up or half_even
+	ZeroAmountPolicy string           //  What an amount_to_pay of zero means: full_debt, forgiven or reject
+	Workers          int              //  How many debts are worked out at once
+}
+
+//  DefaultOptions are the settings the spec calls for
+func DefaultOptions() Options {
+	return Options{
+		Location:         time.UTC,
+		MatchPolicy:      matchExact,
+		Roll:             rollNone,
+		Clock:            systemClock{},
+		StatusThresholds: StatusThresholds{DelinquentAfterDays: 1, BrokenAfterMissed: 3},
+		Waterfall:        strings.Join([]string{componentFees, componentInterest, componentPrincipal}, ","),
+		Rounding:         roundHalfUp,
+		ZeroAmountPolicy: zeroAmountFullDebt,
+		Workers:          runtime.GOMAXPROCS(0),
+	}
+}
+
+//  withDefaults fills in whatever opts leaves out from DefaultOptions
+func (opts Options) withDefaults() Options {
+	defaults := DefaultOptions()
+
+	if opts.Location == nil {
+		opts.Location = defaults.Location
+	}
+	if len(opts.MatchPolicy) == 0 {
+		opts.MatchPolicy = defaults.MatchPolicy
+	}
+	if len(opts.Roll) == 0 {
+		opts.Roll = defaults.Roll
+	}
+	if opts.Clock == nil {
+		opts.Clock = defaults.Clock
+	}
+	if opts.StatusThresholds == (StatusThresholds{}) {
+		opts.StatusThresholds = defaults.StatusThresholds
+	}
+	if len(opts.Waterfall) == 0 {
+		opts.Waterfall = defaults.Waterfall
+	}
+	if len(opts.Rounding) == 0 {
+		opts.Rounding = defaults.Rounding
+	}
+	if len(opts.ZeroAmountPolicy) == 0 {
+		opts.ZeroAmountPolicy = defaults.ZeroAmountPolicy
+	}
+	if opts.Workers == 0 {
+		opts.Workers = defaults.Workers
+	}
+	return opts
+}
+
+//  settings are checked options, parsed into the form the calculations use
+type settings struct {
+	location         *time.Location
+	matchPolicy      MatchPolicy
+	roll             RollConvention
+	asOf             CivilDate
+	clock            Clock
+	statusThresholds StatusThresholds
+	waterfall        []string
+	rounding         string
+	zeroAmountPolicy string
+	workers          int
+}
+
+//  settings checks the options and parses them
+func (opts Options) settings() (settings, error) {
+	var rvalue settings
+	var err error = nil
+
+	opts = opts.withDefaults()
+
+	rvalue.matchPolicy, err = parseMatchPolicy(opts.MatchPolicy)
+	if err != nil {
+		return rvalue, fmt.Errorf("Match policy:%v", err)
+	}
+
+	rvalue.roll, err = parseRollConvention(opts.Roll, opts.Holidays)
+	if err != nil {
+		return rvalue, fmt.Errorf("Roll convention:%v", err)
+	}
+
+	rvalue.waterfall, err = parseWaterfall(opts.Waterfall)
+	if err != nil {
+		return rvalue, fmt.Errorf("Payment waterfall:%v", err)
+	}
+
+	rvalue.rounding, err = parseRoundingMode(opts.Rounding)
+	if err != nil {
+		return rvalue, fmt.Errorf("Rounding mode:%v", err)
+	}
+
+	rvalue.zeroAmountPolicy, err = parseZeroAmountPolicy(opts.ZeroAmountPolicy)
+	if err != nil {
+		return rvalue, fmt.Errorf("Zero amount_to_pay policy:%v", err)
+	}
+
+	err = opts.StatusThresholds.Validate()
+	if err != nil {
+		return rvalue, fmt.Errorf("Status thresholds:%v", err)
+	}
+
+	if opts.Workers < 0 {
+		return rvalue, fmt.Errorf("Workers:Received a negative worker count of %v", opts.Workers)
+	}
+
+	rvalue.location = opts.Location
+	rvalue.asOf = opts.AsOf
+	rvalue.clock = opts.Clock
+	rvalue.statusThresholds = opts.StatusThresholds
+	rvalue.workers = opts.Workers
+	return rvalue, nil
+}
+
+//  Validate checks the options without computing anything
+func (opts Options) Validate() error {
+	_, err := opts.settings()
+	return err
+}
+
+//  newDefaultSettings parses DefaultOptions, which always check out
+func newDefaultSettings() *settings {
+	rvalue, err := DefaultOptions().settings()
+	if err != nil {
+		panic(err)
+	}
+	return &rvalue
+}
+
+//  defaultSettings are what debts that weren't computed as part of a portfolio are worked out with.
+//  Nothing changes them
+var defaultSettings *settings = newDefaultSettings()
+
+//  evaluationDate is the date debts are evaluated as of: the as-of date if one was given,
+//  otherwise today in the business time zone
+func (config *settings) evaluationDate() CivilDate {
+	if !config.asOf.IsZero() {
+		return config.asOf
+	}
+	return civilDateOf(config.clock.Now().In(config.location))
+}
@@ -0,0 +1,138 @@
+package accord
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	paymentSettled  string = "settled"
+	paymentPending  string = "pending"
+	paymentFailed   string = "failed"
+	paymentReversed string = "reversed"
+	paymentRefunded string = "refunded"
+
+	paymentTypePayment    string = "payment"
+	paymentTypeReversal   string = "reversal"   //  Takes back all of the original payment, e.g. a bounced ACH debit
+	paymentTypeChargeback string = "chargeback" //  A reversal the debtor's bank started
+	paymentTypeRefund     string = "refund"     //  Gives back Amount of the original payment
+)
+
+//  normalizePaymentStatus fills in and checks a payment's type and status. Payments that don't say are
+//  settled payments, and reversals, chargebacks and refunds have to say which payment they're for
+func (pmt *Payment) normalizePaymentStatus() error {
+	pmt.Type = strings.ToLower(strings.TrimSpace(pmt.Type))
+	if len(pmt.Type) == 0 {
+		pmt.Type = paymentTypePayment
+	}
+
+	pmt.Status = strings.ToLower(strings.TrimSpace(pmt.Status))
+	if len(pmt.Status) == 0 {
+		pmt.Status = paymentSettled
+	}
+
+	switch pmt.Type {
+	case paymentTypePayment:
+	case paymentTypeReversal, paymentTypeChargeback, paymentTypeRefund:
+		if pmt.OriginalPaymentID == nil {
+			return fmt.Errorf("Received a %v on %v without an original_payment_id", pmt.Type, pmt.Date)
+		}
+	default:
+		return fmt.Errorf("Received unexpected payment type %v", pmt.Type)
+	}
+
+	switch pmt.Status {
+	case paymentSettled, paymentPending, paymentFailed, paymentReversed, paymentRefunded:
+	default:
+		return fmt.Errorf("Received unexpected payment status %v", pmt.Status)
+	}
+	return nil
+}
+
+//  paymentType is the payment's type, treating a blank one as a payment
+func (pmt *Payment) paymentType() string {
+	if len(pmt.Type) == 0 {
+		return paymentTypePayment
+	}
+	return pmt.Type
+}
+
+//  status is the payment's status, treating a blank one as settled
+func (pmt *Payment) status() string {
+	if len(pmt.Status) == 0 {
+		return paymentSettled
+	}
+	return pmt.Status
+}
+
+//  settlePayments works out which of a plan's payments, all dated on or before the as-of date, count
+//  towards the debt. Only settled payments count. A payment that was reversed or charged back doesn't
+//  count at all, which puts whatever installment it paid back to missed, and a refund takes its amount
+//  off the payment it refunds. Failed and reversed payments are returned separately, dated when they
+//  came back, since they're what returned payment fees are charged for. When every payment is a settled
+//  one, they all count as they are and the plan's own slice is returned rather than a copy
+func settlePayments(payments []Payment) ([]Payment, []Payment) {
+	var counted []Payment
+	var returned []Payment
+
+	allSettled := true
+	for idx := range payments {
+		if payments[idx].paymentType() != paymentTypePayment || payments[idx].status() != paymentSettled {
+			allSettled = false
+			break
+		}
+	}
+	if allSettled {
+		//  Capped, so appending to it can't write over the payments after it
+		return payments[:len(payments):len(payments)], returned
+	}
+
+	reversedOn := make(map[int]Payment)
+	refunds := make(map[int][]Payment)
+	for _, pmt := range payments {
+		if pmt.status() != paymentSettled || pmt.OriginalPaymentID == nil {
+			continue
+		}
+		switch pmt.paymentType() {
+		case paymentTypeReversal, paymentTypeChargeback:
+			reversedOn[*pmt.OriginalPaymentID] = pmt
+		case paymentTypeRefund:
+			refunds[*pmt.OriginalPaymentID] = append(refunds[*pmt.OriginalPaymentID], pmt)
+		}
+	}
+
+	for _, pmt := range payments {
+		if pmt.paymentType() != paymentTypePayment {
+			continue
+		}
+
+		if reversal, ok := reversedOn[pmt.ID]; ok && pmt.ID != 0 {
+			pmt.date = reversal.date
+			returned = append(returned, pmt)
+			continue
+		}
+
+		switch pmt.status() {
+		case paymentFailed, paymentReversed:
+			returned = append(returned, pmt)
+			continue
+		case paymentPending, paymentRefunded:
+			continue
+		}
+
+		if pmt.ID != 0 {
+			for _, refund := range refunds[pmt.ID] {
+				pmt.Amount = pmt.Amount.Sub(refund.Amount)
+			}
+			if !pmt.Amount.IsPositive() {
+				continue
+			}
+		}
+		counted = append(counted, pmt)
+	}
+
+	sort.SliceStable(returned, func(i, j int) bool { return returned[i].date.Before(returned[j].date) })
+
+	return counted, returned
+}
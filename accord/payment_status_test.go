@@ -0,0 +1,86 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPayment_normalizePaymentStatus(t *testing.T) {
+	original := 7
+
+	tests := []struct {
+		description string
+		pmt         Payment
+		wantErr     bool
+	}{
+		{"a plain payment", Payment{}, false},
+		{"a refund of another payment", Payment{Type: "Refund", OriginalPaymentID: &original}, false},
+		{"a reversal that doesn't say what it reverses", Payment{Type: paymentTypeReversal}, true},
+		{"an unknown type", Payment{Type: "gift"}, true},
+		{"an unknown status", Payment{Status: "bounced"}, true},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v", test.description)
+		if err := test.pmt.normalizePaymentStatus(); (err != nil) != test.wantErr {
+			t.Errorf("normalizePaymentStatus(), want error:%v, got:%v", test.wantErr, err)
+		}
+	}
+
+	pmt := Payment{}
+	pmt.normalizePaymentStatus()
+	if pmt.Type != paymentTypePayment || pmt.Status != paymentSettled {
+		t.Errorf("normalizePaymentStatus(), want:payment settled, got:%v %v", pmt.Type, pmt.Status)
+	}
+}
+
+func TestSettlePayments(t *testing.T) {
+	makePayment := func(id int, amount int64, date string, pmtType string, status string, original int) Payment {
+		pmt := Payment{ID: id, Amount: decimal.NewFromInt(amount), Date: date, date: mustParseDate(t, date), Type: pmtType, Status: status}
+		if original != 0 {
+			pmt.OriginalPaymentID = &original
+		}
+		return pmt
+	}
+
+	payments := []Payment{
+		makePayment(1, 100, "2021-01-04", "", "", 0),
+		makePayment(2, 100, "2021-01-11", paymentTypePayment, paymentSettled, 0),
+		makePayment(3, 100, "2021-01-18", "", paymentPending, 0),
+		makePayment(4, 100, "2021-01-18", "", paymentFailed, 0),
+		makePayment(5, 100, "2021-01-19", "", "", 0),
+		makePayment(6, 100, "2021-01-22", paymentTypeReversal, "", 2),
+		makePayment(7, 40, "2021-01-23", paymentTypeRefund, "", 5),
+	}
+
+	counted, returned := settlePayments(payments)
+
+	t.Logf("Checking only settled money counts")
+	if len(counted) != 2 || counted[0].ID != 1 || counted[1].ID != 5 || !counted[1].Amount.Equal(decimal.NewFromInt(60)) {
+		t.Errorf("settlePayments() counted, want:payment 1 and $60 of payment 5, got:%v", counted)
+	}
+
+	t.Logf("Checking failed and reversed payments are returned on the day they came back")
+	if len(returned) != 2 || returned[0].ID != 4 || returned[1].ID != 2 || returned[1].date.String() != "2021-01-22" {
+		t.Errorf("settlePayments() returned, want:payments 4 and 2 (on 2021-01-22), got:%v", returned)
+	}
+}
+
+func TestSettlePayments_reversalReinstatesInstallment(t *testing.T) {
+	original := 2
+	payments := []Payment{
+		{ID: 1, Amount: decimal.NewFromInt(100), Date: "2021-01-04"},
+		{ID: 2, Amount: decimal.NewFromInt(100), Date: "2021-01-11"},
+		{ID: 3, Amount: decimal.NewFromInt(100), Date: "2021-01-13", Type: paymentTypeChargeback, OriginalPaymentID: &original},
+	}
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, testPlan(), payments, "2021-12-31")
+
+	delinquency := debt.calculateDelinquency(mustParseDate(t, "2021-01-14"), false)
+	if delinquency.MissedInstallments != 1 || delinquency.DaysPastDue != 3 {
+		t.Errorf("calculateDelinquency(), want the 2021-01-11 installment missed, got:%+v", *delinquency)
+	}
+	if remaining := remainingAmount(t, &debt); !remaining.Equal(decimal.NewFromInt(300)) {
+		t.Errorf("calculateRemainingAmount(), want:300, got:%v", remaining)
+	}
+}
@@ -0,0 +1,240 @@
+package accord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	statusPending    string = "pending"
+	statusActive     string = "active"
+	statusDelinquent string = "delinquent"
+	statusBroken     string = "broken"
+	statusCompleted  string = "completed"
+	statusOverpaid   string = "overpaid"
+)
+
+//  StatusThresholds decide when a plan that has fallen behind is delinquent and when it is broken
+type StatusThresholds struct {
+	DelinquentAfterDays int //  Days an installment can go unpaid once its on-time window is over before the plan is delinquent
+	BrokenAfterMissed   int //  Consecutive missed installments that break the plan
+}
+
+//  Validate refuses thresholds that would make every plan that has started delinquent or broken:
+//  a plan can't be broken before an installment has been missed, or delinquent before one is due
+func (thresholds StatusThresholds) Validate() error {
+	if thresholds.DelinquentAfterDays < 0 {
+		return fmt.Errorf("Received a negative delinquency threshold of %v days", thresholds.DelinquentAfterDays)
+	}
+	if thresholds.BrokenAfterMissed < 1 {
+		return fmt.Errorf("Received a broken plan threshold of %v missed installments; expected at least 1", thresholds.BrokenAfterMissed)
+	}
+	return nil
+}
+
+//  StatusTransition records the date a plan moved into a status
+type StatusTransition struct {
+	Status string    `json:"status"`
+	Date   CivilDate `json:"date"`
+}
+
+//  A plan's lifecycle:
+//  pending     the start date is still in the future
+//  active      payments are keeping up with the schedule
+//  delinquent  an installment is more than DelinquentAfterDays past its on-time window
+//  broken      BrokenAfterMissed installments in a row are past it
+//  The on-time window is the due date plus however many days late the match policy allows
+//  completed   the remaining amount is zero
+//  overpaid    payments came to more than was owed
+//  Statuses are derived from the payment history rather than stored, so a broken plan that
+//  catches up goes back to active
+
+//  planStatusAsOf works out what status the plan was in at the end of date. It relies on the
+//  schedule having been allocated: because payments are applied oldest first, an installment
+//  was already paid on date exactly when it was paid on or before it
+func (debt *Debt) planStatusAsOf(date CivilDate) string {
+	plan := debt.paymentPlan
+	if plan == nil {
+		return ""
+	}
+
+	//  Unpaid installments are always the tail of the schedule, so counting them counts
+	//  consecutive misses
+	thresholds := debt.currentSettings().statusThresholds
+	missed := 0
+	for _, installment := range plan.schedule {
+		if date.daysSince(plan.onTimeThrough(installment)) < thresholds.DelinquentAfterDays {
+			break
+		}
+		if installment.PaidOn == nil || installment.PaidOn.After(date) {
+			missed++
+		}
+	}
+	return debt.planStatusFor(date, debt.remainingAmountAsOf(date), missed)
+}
+
+//  planStatusFor decides the plan's status at the end of date from what was still owed then and how
+//  many installments had gone unpaid past the delinquency threshold
+func (debt *Debt) planStatusFor(date CivilDate, remaining decimal.Decimal, missed int) string {
+	switch {
+	case remaining.IsNegative():
+		return statusOverpaid
+	case remaining.IsZero():
+		return statusCompleted
+	case date.Before(debt.paymentPlan.startDate):
+		return statusPending
+	}
+
+	thresholds := debt.currentSettings().statusThresholds
+	switch {
+	case missed >= thresholds.BrokenAfterMissed:
+		return statusBroken
+	case missed > 0:
+		return statusDelinquent
+	default:
+		return statusActive
+	}
+}
+
+//  calculatePlanStatus works out the plan's status as of asOf and the history of how it got there.
+//  A plan's status can only change on its start date, on a payment date or on the day an installment
+//  crosses the delinquency threshold, so those are the only days we need to look at
+func (debt *Debt) calculatePlanStatus(asOf CivilDate, updateObject bool) (string, []StatusTransition) {
+	if debt.paymentPlan == nil {
+		return "", nil
+	}
+	return debt.planStatusFrom(asOf, debt.calculateBalances(asOf), updateObject)
+}
+
+//  planStatusFrom works out the plan's status history in a single pass over the dates it could change
+//  on, using balances replayed as of asOf. Those are the start date, the days a fee or payment changed
+//  what's owed and the days installments cross the delinquency threshold. The last two are both in
+//  date order already, so they're merged rather than sorted. Going through the dates in order, what's
+//  owed only moves on to later steps, and the installments past the threshold and those paid off both
+//  only grow from the front of the schedule, so nothing is worked out twice
+func (debt *Debt) planStatusFrom(asOf CivilDate, balances *debtBalances, updateObject bool) (string, []StatusTransition) {
+	var history []StatusTransition
+
+	plan := debt.paymentPlan
+	if plan == nil {
+		return "", history
+	}
+
+	//  An installment is past the threshold DelinquentAfterDays after its on-time window ends
+	thresholds := debt.currentSettings().statusThresholds
+	lag := plan.effectiveMatchPolicy().lateDays() + thresholds.DelinquentAfterDays
+	pastThresholdOn := func(idx int) CivilDate {
+		return plan.schedule[idx].DueDate.addDays(lag)
+	}
+
+	remaining := roundAmount(debt.amountToPay(), debt.currency(), debt.currentSettings().rounding)
+	steps := balances.steps
+	pastThreshold, paidOff := 0, 0
+	statusOn := func(date CivilDate) string {
+		for len(steps) > 0 && !steps[0].date.After(date) {
+			remaining = steps[0].remaining
+			steps = steps[1:]
+		}
+		for pastThreshold < len(plan.schedule) && date.daysSince(plan.schedule[pastThreshold].DueDate) >= lag {
+			pastThreshold++
+		}
+		for paidOff < len(plan.schedule) && plan.schedule[paidOff].PaidOn != nil && !plan.schedule[paidOff].PaidOn.After(date) {
+			paidOff++
+		}
+
+		missed := 0
+		if pastThreshold > paidOff {
+			missed = pastThreshold - paidOff
+		}
+		return debt.planStatusFor(date, remaining, missed)
+	}
+
+	started := false
+	stepIdx, dueIdx := 0, 0
+	end := asOf.addDays(1)
+	nextThreshold := end
+	if len(plan.schedule) > 0 {
+		nextThreshold = pastThresholdOn(0)
+	}
+	for {
+		date := end
+		if !started && plan.startDate.Before(date) {
+			date = plan.startDate
+		}
+		if stepIdx < len(balances.steps) && balances.steps[stepIdx].date.Before(date) {
+			date = balances.steps[stepIdx].date
+		}
+		if nextThreshold.Before(date) {
+			date = nextThreshold
+		}
+		if date.After(asOf) {
+			break
+		}
+
+		started = started || plan.startDate == date
+		for stepIdx < len(balances.steps) && balances.steps[stepIdx].date == date {
+			stepIdx++
+		}
+		for nextThreshold == date {
+			dueIdx++
+			nextThreshold = end
+			if dueIdx < len(plan.schedule) {
+				nextThreshold = pastThresholdOn(dueIdx)
+			}
+		}
+
+		status := statusOn(date)
+		if len(history) == 0 || history[len(history)-1].Status != status {
+			history = append(history, StatusTransition{Status: status, Date: date})
+		}
+	}
+
+	rvalue := statusOn(asOf)
+	if len(history) == 0 || history[len(history)-1].Status != rvalue {
+		//  Only a plan that hasn't started yet gets here
+		history = append(history, StatusTransition{Status: rvalue, Date: asOf})
+	}
+
+	if updateObject {
+		debt.PlanStatus = rvalue
+		debt.PlanStatusHistory = history
+	}
+	return rvalue, history
+}
+
+//  completedOn returns the day the plan last completed, as long as it still is complete.
+//  Overpaying completes a plan too
+func (debt *Debt) completedOn() (CivilDate, bool) {
+	var rvalue CivilDate
+	completed := false
+
+	for _, transition := range debt.PlanStatusHistory {
+		paidUp := transition.Status == statusCompleted || transition.Status == statusOverpaid
+		if paidUp && !completed {
+			rvalue = transition.Date
+		}
+		completed = paidUp
+	}
+	return rvalue, completed
+}
+
+//  ParseStatusFilter reads a comma separated list of plan statuses
+func ParseStatusFilter(value string) (map[string]bool, error) {
+	rvalue := make(map[string]bool)
+
+	for _, status := range strings.Split(value, ",") {
+		status = strings.ToLower(strings.TrimSpace(status))
+		switch status {
+		case "":
+			continue
+		case statusPending, statusActive, statusDelinquent, statusBroken, statusCompleted, statusOverpaid:
+			rvalue[status] = true
+		default:
+			return nil, fmt.Errorf("Received unexpected plan status %v", status)
+		}
+	}
+
+	return rvalue, nil
+}
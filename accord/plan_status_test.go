@@ -0,0 +1,116 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDebt_planStatusAsOf(t *testing.T) {
+	config := testSettings()
+	config.statusThresholds = StatusThresholds{DelinquentAfterDays: 1, BrokenAfterMissed: 2}
+
+	plan := testPlan()
+	plan.settings = config
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, plan, []Payment{testPayment("2021-01-04", 100), testPayment("2021-02-01", 300)}, "2021-12-31")
+
+	tests := []struct {
+		description string
+		date        string
+		want        string
+	}{
+		{"before the start date", "2021-01-03", statusPending},
+		{"the first installment paid", "2021-01-04", statusActive},
+		{"on the second due date", "2021-01-11", statusActive},
+		{"a day after a missed installment", "2021-01-12", statusDelinquent},
+		{"two missed installments in a row", "2021-01-19", statusBroken},
+		{"after paying the rest off", "2021-02-01", statusCompleted},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v", test.description)
+		if got := debt.planStatusAsOf(mustParseDate(t, test.date)); got != test.want {
+			t.Errorf("planStatusAsOf(%v), want:%v, got:%v", test.date, test.want, got)
+		}
+	}
+
+	t.Logf("Checking an overpaid plan")
+	debt = makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, plan, []Payment{testPayment("2021-01-04", 450)}, "2021-12-31")
+	if got := debt.planStatusAsOf(mustParseDate(t, "2021-01-05")); got != statusOverpaid {
+		t.Errorf("planStatusAsOf(), want:%v, got:%v", statusOverpaid, got)
+	}
+}
+
+func TestDebt_calculatePlanStatus(t *testing.T) {
+	config := testSettings()
+	config.statusThresholds = StatusThresholds{DelinquentAfterDays: 3, BrokenAfterMissed: 2}
+
+	t.Logf("Checking transitions are dated from the payment history")
+	plan := testPlan()
+	plan.settings = config
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, plan, []Payment{testPayment("2020-12-28", 100), testPayment("2021-01-11", 50), testPayment("2021-02-01", 250)}, "2021-12-31")
+	status, history := debt.calculatePlanStatus(mustParseDate(t, "2021-03-01"), true)
+	if status != statusCompleted || debt.PlanStatus != statusCompleted {
+		t.Errorf("calculatePlanStatus(), want:%v, got:%v", statusCompleted, status)
+	}
+
+	want := []StatusTransition{
+		{statusPending, mustParseDate(t, "2020-12-28")},
+		{statusActive, mustParseDate(t, "2021-01-04")},
+		{statusDelinquent, mustParseDate(t, "2021-01-14")},
+		{statusBroken, mustParseDate(t, "2021-01-21")},
+		{statusCompleted, mustParseDate(t, "2021-02-01")},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("calculatePlanStatus() history, want:%v, got:%v", want, history)
+	}
+	for idx := range want {
+		if history[idx] != want[idx] {
+			t.Errorf("calculatePlanStatus() transition %v, want:%v, got:%v", idx, want[idx], history[idx])
+		}
+	}
+
+	t.Logf("Checking a plan that hasn't started")
+	debt = makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, testPlan(), nil, "2021-12-31")
+	status, history = debt.calculatePlanStatus(mustParseDate(t, "2020-12-01"), false)
+	if status != statusPending || len(history) != 1 {
+		t.Errorf("calculatePlanStatus(), want a single pending status, got:%v %v", status, history)
+	}
+}
+
+func TestStatusThresholds_Validate(t *testing.T) {
+	tests := []struct {
+		thresholds StatusThresholds
+		wantErr    bool
+	}{
+		{StatusThresholds{DelinquentAfterDays: 0, BrokenAfterMissed: 1}, false},
+		{StatusThresholds{DelinquentAfterDays: 1, BrokenAfterMissed: 0}, true},
+		{StatusThresholds{DelinquentAfterDays: -1, BrokenAfterMissed: 3}, true},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %+v", test.thresholds)
+		if err := test.thresholds.Validate(); (err != nil) != test.wantErr {
+			t.Errorf("Validate(), want error:%v, got:%v", test.wantErr, err)
+		}
+
+		options := DefaultOptions()
+		options.StatusThresholds = test.thresholds
+		if err := options.Validate(); (err != nil) != test.wantErr {
+			t.Errorf("Options.Validate(), want error:%v, got:%v", test.wantErr, err)
+		}
+	}
+}
+
+func TestParseStatusFilter(t *testing.T) {
+	t.Logf("Checking a list of statuses")
+	got, err := ParseStatusFilter("active, Broken")
+	if err != nil || !got[statusActive] || !got[statusBroken] || len(got) != 2 {
+		t.Errorf("ParseStatusFilter(), want active and broken, got:%v %v", got, err)
+	}
+
+	t.Logf("Checking an unknown status is rejected")
+	if _, err = ParseStatusFilter("active,late"); err == nil {
+		t.Errorf("ParseStatusFilter() expected an error")
+	}
+}
@@ -0,0 +1,176 @@
+package accord
+
+import (
+	"fmt"
+	"time"
+)
+
+//  Inputs is the raw data a portfolio is computed from, as read from the services
+type Inputs struct {
+	Debts        []Debt        `json:"debts"`
+	PaymentPlans []PaymentPlan `json:"payment_plans"`
+	Payments     []Payment     `json:"payments"`
+}
+
+//  Portfolio is every debt worked out from a set of inputs as of a date. It's never changed once
+//  computed, so it can be shared between goroutines. It doesn't keep the inputs themselves, which
+//  would double what a large portfolio holds on to
+type Portfolio struct {
+	asOf  CivilDate
+	debts map[int]Debt
+}
+
+//  ComputePortfolio checks the inputs, puts each plan under its debt and each payment under its plan,
+//  and works out every debt as of opts.AsOf (today by default). It only reads the inputs, and the same
+//  inputs and options always give the same portfolio
+func ComputePortfolio(inputs Inputs, opts Options) (*Portfolio, error) {
+	config, err := opts.settings()
+	if err != nil {
+		return nil, err
+	}
+
+	rvalue := Portfolio{asOf: config.evaluationDate()}
+	//  Pin the date so that every debt is worked out as of the same day, even across midnight
+	config.asOf = rvalue.asOf
+
+	debts := make(map[int]Debt, len(inputs.Debts))
+	//  Each input is copied as it's read, so nothing the caller goes on to change reaches the portfolio
+	for _, debt := range inputs.Debts {
+		debt = debt.clone()
+		if err = debt.Normalize(); err != nil {
+			return nil, err
+		}
+		debts[debt.ID] = debt
+	}
+
+	//  Keyed by debt id since that's how plans are looked up
+	plans := make(map[int]PaymentPlan, len(inputs.PaymentPlans))
+	for _, plan := range inputs.PaymentPlans {
+		plan = plan.clone()
+		if err = plan.Normalize(); err != nil {
+			return nil, err
+		}
+		if other, ok := plans[plan.DebtID]; ok {
+			return nil, fmt.Errorf("Found payment plans %v and %v for debt %v; expected at most one", other.ID, plan.ID, plan.DebtID)
+		}
+		plans[plan.DebtID] = plan
+	}
+
+	payments := make([]Payment, 0, len(inputs.Payments))
+	for _, pmt := range inputs.Payments {
+		pmt = pmt.clone()
+		if err = pmt.Normalize(config.location); err != nil {
+			return nil, err
+		}
+		payments = append(payments, pmt)
+	}
+	//  normalizeData puts each plan's payments in date order, which is cheaper than sorting all of them
+
+	rvalue.debts, err = normalizeData(debts, plans, payments, &config)
+	if err != nil {
+		return nil, fmt.Errorf("Unexpected error encountered flattening data:%v", err)
+	}
+	return &rvalue, nil
+}
+
+//  AsOf is the date the portfolio was worked out as of
+func (portfolio *Portfolio) AsOf() CivilDate {
+	return portfolio.asOf
+}
+
+//  Debt looks up one debt by id. It's a copy, so changing it leaves the portfolio alone
+func (portfolio *Portfolio) Debt(id int) (Debt, bool) {
+	debt, ok := portfolio.debts[id]
+	return debt.clone(), ok
+}
+
+//  DebtList is the portfolio's debts in id order. A non-empty statuses limits it to debts whose plans
+//  are in those statuses. They're copies, so changing them leaves the portfolio alone
+func (portfolio *Portfolio) DebtList(statuses map[string]bool) []Debt {
+	rvalue := make([]Debt, 0, len(portfolio.debts))
+
+	for _, id := range sortedDebtIDs(portfolio.debts) {
+		debt := portfolio.debts[id]
+		if len(statuses) > 0 && !statuses[debt.PlanStatus] {
+			continue
+		}
+		rvalue = append(rvalue, debt.clone())
+	}
+	return rvalue
+}
+
+//  Refunds lists the debts with a credit balance and the payments that overpaid them
+func (portfolio *Portfolio) Refunds() []RefundDue {
+	return buildRefundsReport(portfolio.debts)
+}
+
+//  Settlements summarizes the debts settled for less, per currency
+func (portfolio *Portfolio) Settlements() []SettlementSummary {
+	return buildSettlementSummary(portfolio.debts)
+}
+
+//  Ledger journals every debt's transactions and checks the trial balance
+func (portfolio *Portfolio) Ledger() Ledger {
+	return buildLedger(portfolio.debts, portfolio.asOf)
+}
+
+//  Statements is each debt's account statement
+func (portfolio *Portfolio) Statements() []AccountStatement {
+	return buildStatements(portfolio.debts, portfolio.asOf)
+}
+
+//  History is every debt's event stream
+func (portfolio *Portfolio) History() []DebtEvent {
+	return buildHistory(portfolio.debts)
+}
+
+//  Replay rebuilds a debt from its events, as of the portfolio's date or, when event is positive,
+//  as it stood after that event
+func (portfolio *Portfolio) Replay(debtID int, event int) (Debt, error) {
+	debt, ok := portfolio.debts[debtID]
+	if !ok {
+		return Debt{}, fmt.Errorf("No debt with id %v", debtID)
+	}
+
+	events := debt.debtEvents()
+	if event > 0 {
+		return replayDebtEventsTo(events, event)
+	}
+	return replayDebtEvents(events, portfolio.asOf)
+}
+
+//  Timelines is the balance timeline of one debt, or of every debt when debtID is negative
+func (portfolio *Portfolio) Timelines(debtID int) ([]Timeline, error) {
+	return buildTimelines(portfolio.debts, debtID)
+}
+
+//  Snapshot is a copy of the inputs the portfolio was computed from and of its debts, for saving as the
+//  run made at timestamp
+func (portfolio *Portfolio) Snapshot(timestamp time.Time, inputs Inputs) RunSnapshot {
+	rvalue := newRunSnapshot(timestamp, portfolio.asOf, inputs.clone(), portfolio.debts)
+
+	for idx := range rvalue.Debts {
+		rvalue.Debts[idx] = rvalue.Debts[idx].clone()
+	}
+	return rvalue
+}
+
+//  clone is a copy of the inputs that shares nothing with them, so the caller can go on changing theirs
+func (inputs *Inputs) clone() Inputs {
+	rvalue := Inputs{
+		Debts:        make([]Debt, 0, len(inputs.Debts)),
+		PaymentPlans: make([]PaymentPlan, 0, len(inputs.PaymentPlans)),
+		Payments:     make([]Payment, 0, len(inputs.Payments)),
+	}
+
+	for _, debt := range inputs.Debts {
+		rvalue.Debts = append(rvalue.Debts, debt.clone())
+	}
+	for _, plan := range inputs.PaymentPlans {
+		rvalue.PaymentPlans = append(rvalue.PaymentPlans, plan.clone())
+	}
+	for _, pmt := range inputs.Payments {
+		rvalue.Payments = append(rvalue.Payments, pmt.clone())
+	}
+	return rvalue
+}
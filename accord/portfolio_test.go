@@ -0,0 +1,428 @@
+package accord
+
+import (
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestComputePortfolio(t *testing.T) {
+	var want map[int]Debt
+
+	err := makeMockGraph(&want)
+	if err != nil {
+		t.Fatalf("ComputePortfolio(), error making mock data: %v", err)
+	}
+
+	debts, plans, payments := getRawTestObjects()
+	var inputs Inputs
+	for _, id := range sortedDebtIDs(debts) {
+		inputs.Debts = append(inputs.Debts, debts[id])
+	}
+	for _, plan := range plans {
+		inputs.PaymentPlans = append(inputs.PaymentPlans, plan)
+	}
+	inputs.Payments = payments
+
+	options := DefaultOptions()
+	options.Clock = testClock
+	portfolio, err := ComputePortfolio(inputs, options)
+	if err != nil {
+		t.Fatalf("ComputePortfolio(), want:no error, got:%v", err)
+	}
+
+	t.Logf("Checking the debts match the ones normalizeData works out")
+	list := portfolio.DebtList(nil)
+	if len(list) != len(want) {
+		t.Fatalf("ComputePortfolio(), want:%v debts, got:%v", len(want), len(list))
+	}
+	for idx, debt := range list {
+		if debt.ID != idx {
+			t.Errorf("DebtList(), want:debt %v, got:%v", idx, debt.ID)
+		}
+		if !debt.RemainingAmount.Equal(want[debt.ID].RemainingAmount) || debt.PlanStatus != want[debt.ID].PlanStatus {
+			t.Errorf("ComputePortfolio() debt %v, want:%v/%v, got:%v/%v", debt.ID, want[debt.ID].RemainingAmount, want[debt.ID].PlanStatus, debt.RemainingAmount, debt.PlanStatus)
+		}
+	}
+
+	t.Logf("Checking the schedule and payments are exposed")
+	debt, ok := portfolio.Debt(4)
+	if plan := debt.PaymentPlan(); plan == nil || len(plan.Schedule()) == 0 || len(plan.Payments()) != 15 {
+		t.Errorf("PaymentPlan(), want:a schedule and 15 payments, got:%v", plan)
+	}
+	if !ok {
+		t.Errorf("Debt(4), want:a debt, got:none")
+	}
+	if plan, _ := portfolio.Debt(10); plan.PaymentPlan() != nil {
+		t.Errorf("PaymentPlan(), want:nil for a debt without a plan, got:%v", plan.PaymentPlan())
+	}
+
+	t.Logf("Checking bad options are rejected")
+	options = DefaultOptions()
+	options.Rounding = "sideways"
+	if _, err = ComputePortfolio(inputs, options); err == nil {
+		t.Errorf("ComputePortfolio(), want:an error for rounding %v, got:none", options.Rounding)
+	}
+	options = DefaultOptions()
+	options.Workers = -1
+	if _, err = ComputePortfolio(inputs, options); err == nil {
+		t.Errorf("ComputePortfolio(), want:an error for %v workers, got:none", options.Workers)
+	}
+
+	plan := PaymentPlan{ID: 1, DebtID: 1, AmountToPay: decimal.NewFromInt(100), InstallmentFrequency: "WEEKLY", InstallmentAmount: decimal.NewFromInt(50), StartDate: "2021-01-04"}
+	duplicate := plan
+	duplicate.ID = 2
+	orphan := plan
+	orphan.ID = 3
+	orphan.DebtID = 2
+
+	t.Logf("Checking a debt with two plans is rejected")
+	bad := Inputs{Debts: []Debt{{ID: 1, Amount: decimal.NewFromInt(100)}}, PaymentPlans: []PaymentPlan{plan, duplicate}}
+	if _, err = ComputePortfolio(bad, DefaultOptions()); err == nil {
+		t.Errorf("ComputePortfolio(), want:an error for two plans on debt 1, got:none")
+	}
+
+	t.Logf("Checking a single orphaned plan is rejected")
+	bad = Inputs{Debts: []Debt{{ID: 1, Amount: decimal.NewFromInt(100)}}, PaymentPlans: []PaymentPlan{plan, orphan}}
+	if _, err = ComputePortfolio(bad, DefaultOptions()); err == nil {
+		t.Errorf("ComputePortfolio(), want:an error for plan %v's missing debt, got:none", orphan.ID)
+	}
+}
+
+func TestComputePortfolio_workers(t *testing.T) {
+	inputs := makeSyntheticInputs(2000, 30000)
+
+	t.Logf("Checking the number of workers doesn't change the results")
+	var want []Debt
+	for _, workers := range []int{1, 3, 16} {
+		options := DefaultOptions()
+		options.AsOf = mustParseDate(t, "2020-09-30")
+		options.Workers = workers
+		portfolio, err := ComputePortfolio(inputs, options)
+		if err != nil {
+			t.Fatalf("ComputePortfolio(), want:no error, got:%v", err)
+		}
+
+		got := portfolio.DebtList(nil)
+		if want == nil {
+			want = got
+			continue
+		}
+		for idx := range want {
+			if !want[idx].RemainingAmount.Equal(got[idx].RemainingAmount) || want[idx].PlanStatus != got[idx].PlanStatus || !want[idx].CreditBalance.Equal(got[idx].CreditBalance) {
+				t.Errorf("ComputePortfolio() with %v workers debt %v, want:%v/%v, got:%v/%v", workers, want[idx].ID, want[idx].RemainingAmount, want[idx].PlanStatus, got[idx].RemainingAmount, got[idx].PlanStatus)
+			}
+		}
+	}
+}
+
+//  makeTestInputs is the mock data as service inputs, with interest on debt 4 written the way a
+//  service might send it
+func makeTestInputs() Inputs {
+	var rvalue Inputs
+
+	debts, plans, payments := getRawTestObjects()
+	for _, id := range sortedDebtIDs(debts) {
+		debt := debts[id]
+		if id == 4 {
+			debt.InterestTerms = &InterestTerms{APR: decimal.NewFromInt(5), Compounding: " Daily "}
+		}
+		rvalue.Debts = append(rvalue.Debts, debt)
+	}
+	for id := 0; id < len(debts); id++ {
+		if plan, ok := plans[id]; ok {
+			rvalue.PaymentPlans = append(rvalue.PaymentPlans, plan)
+		}
+	}
+	rvalue.Payments = payments
+	return rvalue
+}
+
+func TestComputePortfolio_pure(t *testing.T) {
+	inputs := makeTestInputs()
+	untouched := makeTestInputs()
+
+	early := DefaultOptions()
+	early.AsOf = mustParseDate(t, "2020-06-01")
+	late := DefaultOptions()
+	late.AsOf = mustParseDate(t, "2021-06-01")
+	late.Rounding = roundHalfEven
+
+	t.Logf("Checking portfolios with different options can be computed at the same time")
+	results := make([]*Portfolio, 8)
+	var wg sync.WaitGroup
+	for idx := range results {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			options := early
+			if idx%2 == 1 {
+				options = late
+			}
+			portfolio, err := ComputePortfolio(inputs, options)
+			if err != nil {
+				t.Errorf("ComputePortfolio(), want:no error, got:%v", err)
+				return
+			}
+			//  Read it from several goroutines as well
+			portfolio.Ledger()
+			portfolio.Statements()
+			results[idx] = portfolio
+		}(idx)
+	}
+	wg.Wait()
+
+	t.Logf("Checking the inputs weren't changed")
+	if !reflect.DeepEqual(inputs, untouched) {
+		t.Errorf("ComputePortfolio(), want:the inputs left alone, got:%+v", inputs.Debts[4].InterestTerms)
+	}
+
+	t.Logf("Checking the same inputs and options always give the same debts")
+	for idx := 2; idx < len(results); idx++ {
+		if results[idx] == nil || results[idx%2] == nil {
+			continue
+		}
+		want, got := results[idx%2].DebtList(nil), results[idx].DebtList(nil)
+		for pos := range want {
+			if !want[pos].RemainingAmount.Equal(got[pos].RemainingAmount) || want[pos].PlanStatus != got[pos].PlanStatus {
+				t.Errorf("ComputePortfolio() debt %v, want:%v/%v, got:%v/%v", want[pos].ID, want[pos].RemainingAmount, want[pos].PlanStatus, got[pos].RemainingAmount, got[pos].PlanStatus)
+			}
+		}
+	}
+	if results[0] != nil && results[1] != nil {
+		if results[0].AsOf().String() != "2020-06-01" || results[1].AsOf().String() != "2021-06-01" {
+			t.Errorf("AsOf(), want:2020-06-01 and 2021-06-01, got:%v and %v", results[0].AsOf(), results[1].AsOf())
+		}
+		early4, _ := results[0].Debt(4)
+		late4, _ := results[1].Debt(4)
+		if early4.RemainingAmount.Equal(late4.RemainingAmount) {
+			t.Errorf("ComputePortfolio(), want:debt 4 to differ between as-of dates, got:%v for both", early4.RemainingAmount)
+		}
+	}
+
+	t.Logf("Checking a returned plan is a copy")
+	if debt, ok := results[1].Debt(4); ok {
+		debt.PaymentPlan().InstallmentAmount = decimal.NewFromInt(1)
+		if again, _ := results[1].Debt(4); again.PaymentPlan().InstallmentAmount.Equal(decimal.NewFromInt(1)) {
+			t.Errorf("PaymentPlan(), want:a copy, got:the portfolio's plan")
+		}
+	}
+}
+
+func TestComputePortfolio_copies(t *testing.T) {
+	//  A $400 plan of $100 a week with fees and interest that has missed two installments by 2021-02-01
+	inputs := Inputs{
+		Debts:        []Debt{{ID: 1, Amount: decimal.NewFromInt(400), InterestTerms: &InterestTerms{APR: decimal.NewFromInt(10), Compounding: compoundingSimple, DayCount: dayCountActual365}}},
+		PaymentPlans: []PaymentPlan{{ID: 1, DebtID: 1, AmountToPay: decimal.NewFromInt(400), InstallmentFrequency: "WEEKLY", InstallmentAmount: decimal.NewFromInt(100), StartDate: "2021-01-04", FeeSchedule: &FeeSchedule{LateFee: decimal.NewFromInt(25)}}},
+		Payments:     []Payment{{ID: 1, PaymentPlanID: 1, Amount: decimal.NewFromInt(100), Date: "2021-01-04"}, {ID: 2, PaymentPlanID: 1, Amount: decimal.NewFromInt(100), Date: "2021-01-11"}},
+	}
+	options := DefaultOptions()
+	options.AsOf = mustParseDate(t, "2021-02-01")
+
+	portfolio, err := ComputePortfolio(inputs, options)
+	if err != nil {
+		t.Fatalf("ComputePortfolio(), want:no error, got:%v", err)
+	}
+	want, _ := portfolio.Debt(1)
+	if want.Delinquency == nil || want.NextPaymentDate == nil || want.Fees == nil || want.InterestTerms == nil || len(want.PlanStatusHistory) == 0 {
+		t.Fatalf("ComputePortfolio(), want:a delinquent debt with fees and interest, got:%+v", want)
+	}
+	wantMissed := want.Delinquency.MissedInstallments
+	wantNext := *want.NextPaymentDate
+	wantStatus := want.PlanStatusHistory[0].Status
+
+	t.Logf("Checking changing the caller's inputs leaves the portfolio alone")
+	saved := portfolio.Snapshot(time.Now(), inputs)
+	inputs.Debts[0].InterestTerms.APR = decimal.NewFromInt(99)
+	inputs.PaymentPlans[0].FeeSchedule.LateFee = decimal.NewFromInt(99)
+	if debt, _ := portfolio.Debt(1); !debt.InterestTerms.APR.Equal(decimal.NewFromInt(10)) || !debt.PaymentPlan().FeeSchedule.LateFee.Equal(decimal.NewFromInt(25)) {
+		t.Errorf("ComputePortfolio(), want:the inputs it was computed from, got:%+v %+v", *debt.InterestTerms, *debt.PaymentPlan().FeeSchedule)
+	}
+	if !saved.Inputs.Debts[0].InterestTerms.APR.Equal(decimal.NewFromInt(10)) || !saved.Inputs.PaymentPlans[0].FeeSchedule.LateFee.Equal(decimal.NewFromInt(25)) {
+		t.Errorf("Snapshot(), want:a copy of the inputs, got:%+v %+v", *saved.Inputs.Debts[0].InterestTerms, *saved.Inputs.PaymentPlans[0].FeeSchedule)
+	}
+
+	t.Logf("Checking changing a returned debt leaves the portfolio alone")
+	for _, debt := range []Debt{want, portfolio.DebtList(nil)[0]} {
+		debt.Delinquency.MissedInstallments = 99
+		*debt.NextPaymentDate = "1999-01-01"
+		debt.PlanStatusHistory[0].Status = "changed"
+		debt.InterestTerms.APR = decimal.NewFromInt(99)
+		debt.Fees.FeesAssessed = decimal.NewFromInt(99)
+		debt.PaymentPlan().FeeSchedule.LateFee = decimal.NewFromInt(99)
+	}
+	snapshot := portfolio.Snapshot(time.Now(), inputs)
+	snapshot.Debts[0].Delinquency.MissedInstallments = 99
+
+	got, _ := portfolio.Debt(1)
+	if got.Delinquency.MissedInstallments != wantMissed {
+		t.Errorf("Debt() missed installments, want:%v, got:%v", wantMissed, got.Delinquency.MissedInstallments)
+	}
+	if *got.NextPaymentDate != wantNext {
+		t.Errorf("Debt() next payment date, want:%v, got:%v", wantNext, *got.NextPaymentDate)
+	}
+	if got.PlanStatusHistory[0].Status != wantStatus {
+		t.Errorf("Debt() plan status history, want:%v, got:%v", wantStatus, got.PlanStatusHistory[0].Status)
+	}
+	if !got.InterestTerms.APR.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("Debt() interest terms, want:10, got:%v", got.InterestTerms.APR)
+	}
+	if got.Fees.FeesAssessed.Equal(decimal.NewFromInt(99)) {
+		t.Errorf("Debt() fees assessed, want:unchanged, got:%v", got.Fees.FeesAssessed)
+	}
+	if !got.PaymentPlan().FeeSchedule.LateFee.Equal(decimal.NewFromInt(25)) {
+		t.Errorf("PaymentPlan() late fee, want:25, got:%v", got.PaymentPlan().FeeSchedule.LateFee)
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	t.Logf("Checking a debt")
+	debt, err := NewDebt(1, decimal.NewFromInt(100), " eur ")
+	if err != nil || debt.Currency != "EUR" {
+		t.Errorf("NewDebt(), want:EUR, got:%v %v", debt.Currency, err)
+	}
+	if _, err = NewDebt(1, decimal.NewFromInt(-1), ""); err == nil {
+		t.Errorf("NewDebt(), want:an error for a negative amount, got:none")
+	}
+
+	t.Logf("Checking a payment plan")
+	plan, err := NewPaymentPlan(1, 1, decimal.NewFromInt(100), "weekly", decimal.NewFromInt(25), "2021-01-04")
+	if err != nil || plan.startDate.String() != "2021-01-04" {
+		t.Errorf("NewPaymentPlan(), want:2021-01-04, got:%v %v", plan.startDate, err)
+	}
+	if _, err = NewPaymentPlan(1, 1, decimal.NewFromInt(100), "fortnightly-ish", decimal.NewFromInt(25), "2021-01-04"); err == nil {
+		t.Errorf("NewPaymentPlan(), want:an error for an unknown frequency, got:none")
+	}
+	if _, err = NewPaymentPlan(1, 1, decimal.NewFromInt(100), "weekly", decimal.Zero, "2021-01-04"); err == nil {
+		t.Errorf("NewPaymentPlan(), want:an error for a zero installment, got:none")
+	}
+	if _, err = NewPaymentPlan(1, 1, decimal.NewFromInt(100), "weekly", decimal.NewFromInt(25), ""); err == nil {
+		t.Errorf("NewPaymentPlan(), want:an error for a missing start date, got:none")
+	}
+
+	t.Logf("Checking a payment")
+	pmt, err := NewPayment(1, 1, decimal.NewFromInt(25), "2021-01-04")
+	if err != nil || pmt.BusinessDate().String() != "2021-01-04" || pmt.status() != paymentSettled {
+		t.Errorf("NewPayment(), want:a settled payment on 2021-01-04, got:%v %v", pmt, err)
+	}
+	if _, err = NewPayment(1, 1, decimal.NewFromInt(25), "someday"); err == nil {
+		t.Errorf("NewPayment(), want:an error for a bad date, got:none")
+	}
+}
+
+//  The size of portfolio the calculations are expected to handle, and what each debt in it may cost. The
+//  nightly run has a budget of two minutes of one core's time and 8GiB of heap for a million debts, which
+//  is half of a 16GiB worker; the other half is for the inputs and the garbage collector. Benchmarks run
+//  with -short use a hundredth of the size and check the same targets, which a join that scans the payments
+//  for every debt or workers that don't run side by side would both miss
+const (
+	syntheticDebts       int           = 1000000
+	syntheticPayments    int           = 20000000
+	computeTimePerDebt   time.Duration = 2 * time.Minute / time.Duration(syntheticDebts) //  Elapsed time per debt times the cores the workers run on
+	computeMemoryPerDebt uint64        = 8 << 30 / uint64(syntheticDebts)                //  Heap still in use per debt once the portfolio is computed
+)
+
+//  makeSyntheticInputs makes a portfolio of debts, nine in ten with a weekly plan, and payments spread
+//  evenly over those plans. The payments come interleaved across plans in date order, the way a
+//  payments service would list them
+func makeSyntheticInputs(debtCount int, paymentCount int) Inputs {
+	random := rand.New(rand.NewSource(1))
+	start := CivilDate{2020, time.January, 6}
+
+	//  Dates are formatted once and shared, since there are far fewer dates than payments
+	dates := make([]string, 366+7*(paymentCount/debtCount+2))
+	for idx := range dates {
+		dates[idx] = start.addDays(idx).String()
+	}
+
+	rvalue := Inputs{Debts: make([]Debt, 0, debtCount)}
+	var installments []decimal.Decimal
+	var offsets []int
+	for id := 0; id < debtCount; id++ {
+		amount := decimal.NewFromInt(int64(1000 + random.Intn(9000)))
+		rvalue.Debts = append(rvalue.Debts, Debt{ID: id, Amount: amount})
+		if id%10 == 9 {
+			continue
+		}
+
+		offset := random.Intn(365)
+		installment := amount.Div(decimal.NewFromInt(40)).Round(2)
+		rvalue.PaymentPlans = append(rvalue.PaymentPlans, PaymentPlan{ID: id, DebtID: id, AmountToPay: amount, InstallmentFrequency: "weekly", InstallmentAmount: installment, StartDate: dates[offset]})
+		installments = append(installments, installment)
+		offsets = append(offsets, offset)
+	}
+
+	rvalue.Payments = make([]Payment, 0, paymentCount)
+	for week := 0; len(rvalue.Payments) < paymentCount; week++ {
+		for idx, plan := range rvalue.PaymentPlans {
+			if len(rvalue.Payments) == paymentCount {
+				break
+			}
+			rvalue.Payments = append(rvalue.Payments, Payment{ID: len(rvalue.Payments) + 1, PaymentPlanID: plan.ID, Amount: installments[idx], Date: dates[offsets[idx]+7*week]})
+		}
+	}
+	return rvalue
+}
+
+func BenchmarkComputePortfolio(b *testing.B) {
+	debtCount, paymentCount := syntheticDebts, syntheticPayments
+	if testing.Short() {
+		debtCount, paymentCount = debtCount/100, paymentCount/100
+	}
+
+	inputs := makeSyntheticInputs(debtCount, paymentCount)
+	options := DefaultOptions()
+	options.AsOf = CivilDate{2021, time.December, 31}
+
+	var before runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var portfolio *Portfolio
+	var err error
+	started := time.Now()
+	for n := 0; n < b.N; n++ {
+		portfolio, err = ComputePortfolio(inputs, options)
+		if err != nil {
+			b.Fatalf("ComputePortfolio(), want:no error, got:%v", err)
+		}
+	}
+	b.StopTimer()
+	elapsed := time.Since(started) / time.Duration(b.N)
+
+	var after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(portfolio)
+
+	retained := uint64(0)
+	if after.HeapAlloc > before.HeapAlloc {
+		retained = after.HeapAlloc - before.HeapAlloc
+	}
+	//  Workers that really run side by side divide the elapsed time by the cores they have
+	cores := options.Workers
+	if procs := runtime.GOMAXPROCS(0); procs < cores {
+		cores = procs
+	}
+	perDebt := elapsed * time.Duration(cores) / time.Duration(debtCount)
+	retainedPerDebt := retained / uint64(debtCount)
+
+	b.ReportMetric(float64(elapsed.Nanoseconds())/float64(debtCount), "ns/debt")
+	b.ReportMetric(float64(perDebt.Nanoseconds()), "core-ns/debt")
+	b.ReportMetric(float64(retainedPerDebt), "heap-bytes/debt")
+
+	if perDebt > computeTimePerDebt {
+		b.Errorf("ComputePortfolio() of %v debts and %v payments on %v cores, want:under %v per debt, got:%v", debtCount, paymentCount, cores, computeTimePerDebt, perDebt)
+	}
+	if retainedPerDebt > computeMemoryPerDebt {
+		b.Errorf("ComputePortfolio() of %v debts and %v payments, want:under %v bytes of heap per debt, got:%v", debtCount, paymentCount, computeMemoryPerDebt, retainedPerDebt)
+	}
+}
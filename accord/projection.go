@@ -0,0 +1,95 @@
+package accord
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+//  PayoffProjection is when and how an active plan is expected to finish if the debtor
+//  pays each remaining installment as scheduled
+type PayoffProjection struct {
+	PayoffDate             CivilDate
+	RemainingInstallments  int
+	FinalInstallmentAmount decimal.Decimal
+}
+
+//  projectPayoff assumes the debtor pays installment_amount on every due date from next_payment_due_date
+//  on, until the remaining amount is used up. The installment that uses it up is the final one and pays
+//  only what is left, so extra payments that brought the balance down show up as fewer installments, an
+//  earlier payoff date and a smaller final installment.
+//  A plan that is behind can't pay on dates that have already gone by, so its projection starts at the
+//  first due date on or after the as-of date, and that first installment also catches up the arrears.
+//  Returns false when the debt has no active plan or can't be paid off at the plan's terms
+func (debt *Debt) projectPayoff(updateObject bool) (PayoffProjection, bool) {
+	remaining, _ := debt.calculateRemainingAmount(false)
+	return debt.projectPayoffFrom(remaining, updateObject)
+}
+
+//  projectPayoffFrom is projectPayoff for a remaining amount that's already been worked out
+func (debt *Debt) projectPayoffFrom(remaining decimal.Decimal, updateObject bool) (PayoffProjection, bool) {
+	var rvalue PayoffProjection
+
+	if !debt.planActiveFrom(remaining) {
+		return rvalue, false
+	}
+
+	plan := debt.paymentPlan
+
+	recurrence, err := parseRecurrence(plan.InstallmentFrequency)
+	if err != nil || !plan.InstallmentAmount.IsPositive() {
+		return rvalue, false
+	}
+
+	dueDate, ok := plan.nextDueDate()
+	if !ok {
+		return rvalue, false
+	}
+
+	asOf := debt.evaluatedAsOf()
+	if dueDate.Before(asOf) {
+		dueDate = plan.nextDueDateAfter(recurrence, asOf.addDays(-1))
+	}
+
+	//  Whatever fell due before then and hasn't been paid is owed on top of the first installment
+	arrears := decimal.Zero
+	for _, installment := range plan.schedule {
+		if !installment.DueDate.Before(dueDate) {
+			break
+		}
+		if unpaid := installment.unpaid(); unpaid.IsPositive() {
+			arrears = addAmount(arrears, unpaid)
+		}
+	}
+
+	balance := remaining
+
+	installmentAmount := addAmount(plan.InstallmentAmount, arrears)
+	for {
+		rvalue.RemainingInstallments++
+		rvalue.PayoffDate = dueDate
+
+		if !balance.GreaterThan(installmentAmount) {
+			rvalue.FinalInstallmentAmount = balance
+			break
+		}
+		balance = balance.Sub(installmentAmount)
+		installmentAmount = plan.InstallmentAmount
+		dueDate = plan.nextDueDateAfter(recurrence, dueDate)
+	}
+
+	debt.storePayoffProjection(rvalue, updateObject)
+	return rvalue, true
+}
+
+//  storePayoffProjection copies a projection onto the debt's output fields
+func (debt *Debt) storePayoffProjection(projection PayoffProjection, updateObject bool) {
+	if !updateObject {
+		return
+	}
+	payoffDate := projection.PayoffDate.String()
+	remainingInstallments := projection.RemainingInstallments
+	finalInstallmentAmount := projection.FinalInstallmentAmount
+
+	debt.ProjectedPayoffDate = &payoffDate
+	debt.RemainingInstallments = &remainingInstallments
+	debt.FinalInstallmentAmount = &finalInstallmentAmount
+}
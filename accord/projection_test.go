@@ -0,0 +1,113 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDebt_projectPayoff(t *testing.T) {
+	tests := []struct {
+		description     string
+		debtID          int
+		asOf            string
+		wantDate        string
+		wantRemaining   int
+		wantFinalAmount string
+	}{
+		{"a plan part way through its schedule", 4, "2020-09-12", "2021-01-15", 9, "2.02"},
+		{"a plan with a zero amount_to_pay runs on the debt amount", 1, "2020-01-31", "2020-03-20", 8, "9"},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v", test.description)
+		var debts map[int]Debt
+		config := testSettings()
+		config.asOf = mustParseDate(t, test.asOf)
+		if err := makeMockGraphWith(&debts, config); err != nil {
+			t.Fatalf("projectPayoff(), error making mock data: %v", err)
+		}
+
+		debt := debts[test.debtID]
+		got, ok := debt.projectPayoff(false)
+		if !ok {
+			t.Errorf("projectPayoff() expected a projection for debt %v", test.debtID)
+			continue
+		}
+		if got.PayoffDate.String() != test.wantDate {
+			t.Errorf("projectPayoff() payoff date, want:%v, got:%v", test.wantDate, got.PayoffDate)
+		}
+		if got.RemainingInstallments != test.wantRemaining {
+			t.Errorf("projectPayoff() remaining installments, want:%v, got:%v", test.wantRemaining, got.RemainingInstallments)
+		}
+		if want := decimal.RequireFromString(test.wantFinalAmount); !got.FinalInstallmentAmount.Equal(want) {
+			t.Errorf("projectPayoff() final installment, want:%v, got:%v", want, got.FinalInstallmentAmount)
+		}
+	}
+
+	t.Logf("Checking a paid off debt has no projection")
+	var debts map[int]Debt
+	if err := makeMockGraph(&debts); err != nil {
+		t.Fatalf("projectPayoff(), error making mock data: %v", err)
+	}
+	debt := debts[9]
+	if _, ok := debt.projectPayoff(false); ok {
+		t.Errorf("projectPayoff() didn't expect a projection for a paid off debt")
+	}
+}
+
+func TestDebt_projectPayoff_extraPayment(t *testing.T) {
+	plan := testPlan()
+	plan.AmountToPay = decimal.NewFromInt(1000)
+	makeDebt := func(asOf string, payments ...Payment) Debt {
+		return makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(1000)}, plan, payments, asOf)
+	}
+
+	t.Logf("Checking a plan paid on schedule")
+	debt := makeDebt("2021-01-04", testPayment("2021-01-04", 100))
+	got, _ := debt.projectPayoff(false)
+	if got.RemainingInstallments != 9 || got.PayoffDate.String() != "2021-03-08" || !got.FinalInstallmentAmount.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("projectPayoff(), want 9 installments to 2021-03-08 ending with 100, got:%v to %v ending with %v", got.RemainingInstallments, got.PayoffDate, got.FinalInstallmentAmount)
+	}
+
+	//  The extra $250 pays the next two installments and half of the one after, so
+	//  they owe nothing until 2021-01-25 and the last installment shrinks to $50
+	t.Logf("Checking an extra unscheduled payment")
+	debt = makeDebt("2021-01-06", testPayment("2021-01-04", 100), testPayment("2021-01-06", 250))
+	got, _ = debt.projectPayoff(false)
+	if got.RemainingInstallments != 7 || got.PayoffDate.String() != "2021-03-08" || !got.FinalInstallmentAmount.Equal(decimal.NewFromInt(50)) {
+		t.Errorf("projectPayoff(), want 7 installments to 2021-03-08 ending with 50, got:%v to %v ending with %v", got.RemainingInstallments, got.PayoffDate, got.FinalInstallmentAmount)
+	}
+
+	//  2021-01-11 and 2021-01-18 have gone by unpaid, so the $200 they owed is added to the
+	//  installment due on 2021-01-25
+	t.Logf("Checking a plan that is behind catches up on the first due date after the as-of date")
+	debt = makeDebt("2021-01-20", testPayment("2021-01-04", 100))
+	got, _ = debt.projectPayoff(false)
+	if got.RemainingInstallments != 7 || got.PayoffDate.String() != "2021-03-08" || !got.FinalInstallmentAmount.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("projectPayoff(), want 7 installments to 2021-03-08 ending with 100, got:%v to %v ending with %v", got.RemainingInstallments, got.PayoffDate, got.FinalInstallmentAmount)
+	}
+
+	t.Logf("Checking the projection is stored on the debt")
+	debt = makeDebt("2021-01-06", testPayment("2021-01-04", 100), testPayment("2021-01-06", 250))
+	debt.projectPayoff(true)
+	if debt.ProjectedPayoffDate == nil || *debt.ProjectedPayoffDate != "2021-03-08" || debt.RemainingInstallments == nil || *debt.RemainingInstallments != 7 {
+		t.Errorf("projectPayoff(true) didn't update the debt")
+	}
+}
+
+func TestDebt_projectPayoff_behind(t *testing.T) {
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, testPlan(), []Payment{testPayment("2021-01-04", 100)}, "2021-06-01")
+
+	//  Every installment fell due months ago, so the whole $300 is owed on the next weekly date
+	t.Logf("Checking a plan whose whole schedule has gone by isn't projected into the past")
+	if debt.ProjectedPayoffDate == nil || *debt.ProjectedPayoffDate != "2021-06-07" {
+		t.Errorf("projectPayoff(), want:2021-06-07, got:%v", debt.ProjectedPayoffDate)
+	}
+	if debt.RemainingInstallments == nil || *debt.RemainingInstallments != 1 || !debt.FinalInstallmentAmount.Equal(decimal.NewFromInt(300)) {
+		t.Errorf("projectPayoff(), want:1 installment of 300, got:%v of %v", debt.RemainingInstallments, debt.FinalInstallmentAmount)
+	}
+	if debt.Delinquency == nil || debt.Delinquency.DaysPastDue != 141 {
+		t.Errorf("calculateDelinquency(), want:141 days past due, got:%+v", debt.Delinquency)
+	}
+}
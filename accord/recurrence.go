@@ -0,0 +1,166 @@
+package accord
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	weekly            string = "weekly"
+	biweekly          string = "bi_weekly"
+	monthly           string = "monthly"
+	semiMonthly       string = "semi_monthly"
+	quarterly         string = "quarterly"
+	everyNDaysPrefix  string = "every_"
+	everyNDaysSuffix  string = "_days"
+	semiMonthlyPrefix string = "semi_monthly:"
+)
+
+//  recurrenceUnit is the calendar unit a Recurrence steps in
+type recurrenceUnit int
+
+const (
+	recurrenceDays recurrenceUnit = iota
+	recurrenceMonths
+	recurrenceSemiMonthly
+)
+
+//  Recurrence describes the calendar on which installments of a payment plan
+//  fall due. Unlike a time.Duration it can express months, so monthly and quarterly
+//  plans stay anchored to the start date's day of month (clamped to the end of
+//  shorter months) instead of drifting by a fixed number of hours.
+type Recurrence struct {
+	unit     recurrenceUnit
+	interval int    //  Days or months between installments
+	days     [2]int //  Days of the month used by semi-monthly plans
+}
+
+//  parseRecurrence converts an installment_frequency into a Recurrence. Besides
+//  weekly and bi_weekly we accept monthly, quarterly, semi_monthly (the 1st and 15th),
+//  semi_monthly:D1,D2 for custom days of the month and every_N_days
+func parseRecurrence(freq string) (Recurrence, error) {
+	var rvalue Recurrence
+
+	//  Normalize our comparison to lowercase; you never know what those Scala services are up to 😁
+	frequency := strings.ToLower(strings.TrimSpace(freq))
+
+	switch {
+	case frequency == weekly:
+		rvalue = Recurrence{unit: recurrenceDays, interval: 7}
+	case frequency == biweekly:
+		rvalue = Recurrence{unit: recurrenceDays, interval: 14}
+	case frequency == monthly:
+		rvalue = Recurrence{unit: recurrenceMonths, interval: 1}
+	case frequency == quarterly:
+		rvalue = Recurrence{unit: recurrenceMonths, interval: 3}
+	case frequency == semiMonthly:
+		rvalue = Recurrence{unit: recurrenceSemiMonthly, days: [2]int{1, 15}}
+	case strings.HasPrefix(frequency, semiMonthlyPrefix):
+		parts := strings.Split(strings.TrimPrefix(frequency, semiMonthlyPrefix), ",")
+		if len(parts) != 2 {
+			return rvalue, fmt.Errorf("Received unexpected value of %v in payment frequency", freq)
+		}
+		first, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+		second, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err1 != nil || err2 != nil || first < 1 || second > 31 || first >= second {
+			return rvalue, fmt.Errorf("Received unexpected value of %v in payment frequency", freq)
+		}
+		rvalue = Recurrence{unit: recurrenceSemiMonthly, days: [2]int{first, second}}
+	case strings.HasPrefix(frequency, everyNDaysPrefix) && strings.HasSuffix(frequency, everyNDaysSuffix):
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(frequency, everyNDaysPrefix), everyNDaysSuffix))
+		if err != nil || n < 1 {
+			return rvalue, fmt.Errorf("Received unexpected value of %v in payment frequency", freq)
+		}
+		rvalue = Recurrence{unit: recurrenceDays, interval: n}
+	default:
+		//  punt if we got something unexpected
+		return rvalue, fmt.Errorf("Received unexpected value of %v in payment frequency", freq)
+	}
+
+	return rvalue, nil
+}
+
+//  occurrence returns the n-th due date of the recurrence, where occurrence 0 is the start date.
+//  Every date is computed from the start date rather than from the previous occurrence so that
+//  a plan starting on the 31st comes back to the 31st after passing through February
+func (r Recurrence) occurrence(start CivilDate, n int) CivilDate {
+	if n <= 0 {
+		return start
+	}
+
+	switch r.unit {
+	case recurrenceMonths:
+		return start.addMonthsClamped(n*r.interval, start.Day)
+	case recurrenceSemiMonthly:
+		//  Work out which semi-monthly slot comes first after the start date, then
+		//  step through the slots two to a month
+		base := 2
+		if start.Day < clampDay(start.Year, start.Month, r.days[0]) {
+			base = 0
+		} else if start.Day < clampDay(start.Year, start.Month, r.days[1]) {
+			base = 1
+		}
+		slot := base + n - 1
+		return start.addMonthsClamped(slot/2, r.days[slot%2])
+	default:
+		return start.addDays(n * r.interval)
+	}
+}
+
+//  indexOnOrBefore returns the index of the last occurrence falling on or before date,
+//  or -1 if date is before the start date
+func (r Recurrence) indexOnOrBefore(start CivilDate, date CivilDate) int {
+	if date.Before(start) {
+		return -1
+	}
+
+	//  Estimate the index, then walk it into place
+	var n int
+	switch r.unit {
+	case recurrenceMonths:
+		n = monthsBetween(start, date) / r.interval
+	case recurrenceSemiMonthly:
+		n = monthsBetween(start, date) * 2
+	default:
+		n = date.daysSince(start) / r.interval
+	}
+
+	for n > 0 && r.occurrence(start, n).After(date) {
+		n--
+	}
+	for !r.occurrence(start, n+1).After(date) {
+		n++
+	}
+	return n
+}
+
+//  nextAfter returns the first occurrence strictly after date
+func (r Recurrence) nextAfter(start CivilDate, date CivilDate) CivilDate {
+	return r.occurrence(start, r.indexOnOrBefore(start, date)+1)
+}
+
+//  lastOnOrBefore returns the last occurrence on or before date. Dates that come
+//  before the start date get the start date back
+func (r Recurrence) lastOnOrBefore(start CivilDate, date CivilDate) CivilDate {
+	idx := r.indexOnOrBefore(start, date)
+	if idx < 0 {
+		return start
+	}
+	return r.occurrence(start, idx)
+}
+
+//  clampDay limits day to the number of days in the given month
+func clampDay(year int, month time.Month, day int) int {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > lastDay {
+		return lastDay
+	}
+	return day
+}
+
+//  monthsBetween counts the calendar months from d1 to d2, ignoring the day of month
+func monthsBetween(d1 CivilDate, d2 CivilDate) int {
+	return (d2.Year-d1.Year)*12 + int(d2.Month) - int(d1.Month)
+}
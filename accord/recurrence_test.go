@@ -0,0 +1,87 @@
+package accord
+
+import (
+	"testing"
+)
+
+func mustParseDate(t *testing.T, value string) CivilDate {
+	rvalue, err := ParseCivilDate(value)
+	if err != nil {
+		t.Fatalf("error parsing test date %v:%v", value, err)
+	}
+	return rvalue
+}
+
+func TestRecurrence_occurrence(t *testing.T) {
+	tests := []struct {
+		description string
+		frequency   string
+		start       string
+		n           int
+		want        string
+	}{
+		{"weekly", "weekly", "2020-01-31", 2, "2020-02-14"},
+		{"bi-weekly", "bi_weekly", "2020-01-31", 2, "2020-02-28"},
+		{"monthly clamps to the end of February", "monthly", "2020-01-31", 1, "2020-02-29"},
+		{"monthly comes back to the 31st after February", "monthly", "2020-01-31", 2, "2020-03-31"},
+		{"monthly clamps to the end of a 30 day month", "monthly", "2021-01-31", 3, "2021-04-30"},
+		{"quarterly", "quarterly", "2020-11-30", 1, "2021-02-28"},
+		{"quarterly across a year", "Quarterly", "2020-11-30", 4, "2021-11-30"},
+		{"semi-monthly after a start on the 1st", "semi_monthly", "2020-01-01", 1, "2020-01-15"},
+		{"semi-monthly rolls into the next month", "semi_monthly", "2020-01-01", 2, "2020-02-01"},
+		{"semi-monthly after a start between slots", "semi_monthly", "2020-01-10", 1, "2020-01-15"},
+		{"semi-monthly after a start past both slots", "semi_monthly", "2020-01-20", 1, "2020-02-01"},
+		{"semi-monthly with custom days clamps to February", "semi_monthly:14,30", "2021-02-01", 2, "2021-02-28"},
+		{"every N days", "every_10_days", "2020-12-25", 1, "2021-01-04"},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v", test.description)
+		recurrence, err := parseRecurrence(test.frequency)
+		if err != nil {
+			t.Errorf("parseRecurrence(%v) unexpected error:%v", test.frequency, err)
+			continue
+		}
+		got := recurrence.occurrence(mustParseDate(t, test.start), test.n)
+		want := mustParseDate(t, test.want)
+		if got != want {
+			t.Errorf("occurrence(), want:%v, got:%v", want, got)
+		}
+	}
+}
+
+func TestRecurrence_nextAfter(t *testing.T) {
+	t.Logf("Checking the next monthly date from a clamped end-of-month date")
+	recurrence, err := parseRecurrence("monthly")
+	if err != nil {
+		t.Fatalf("parseRecurrence() unexpected error:%v", err)
+	}
+	start := mustParseDate(t, "2020-01-31")
+	got := recurrence.nextAfter(start, mustParseDate(t, "2020-02-29"))
+	want := mustParseDate(t, "2020-03-31")
+	if got != want {
+		t.Errorf("nextAfter(), want:%v, got:%v", want, got)
+	}
+
+	t.Logf("Checking the last date on or before a date that falls between installments")
+	got = recurrence.lastOnOrBefore(start, mustParseDate(t, "2020-04-15"))
+	want = mustParseDate(t, "2020-03-31")
+	if got != want {
+		t.Errorf("lastOnOrBefore(), want:%v, got:%v", want, got)
+	}
+
+	t.Logf("Checking a date before the plan starts")
+	got = recurrence.nextAfter(start, mustParseDate(t, "2019-12-01"))
+	if got != start {
+		t.Errorf("nextAfter(), want:%v, got:%v", start, got)
+	}
+}
+
+func TestParseRecurrence_invalid(t *testing.T) {
+	for _, freq := range []string{"fortnightly", "every_0_days", "semi_monthly:15,1", "semi_monthly:1", ""} {
+		t.Logf("Checking that %q is rejected", freq)
+		if _, err := parseRecurrence(freq); err == nil {
+			t.Errorf("parseRecurrence(%q) expected an error", freq)
+		}
+	}
+}
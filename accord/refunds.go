@@ -0,0 +1,58 @@
+package accord
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+//  RefundDue is a debt that has been paid more than was owed, and the payments that overpaid it
+type RefundDue struct {
+	DebtID        int             `json:"debt_id"`
+	Currency      string          `json:"currency"`
+	CreditBalance decimal.Decimal `json:"credit_balance"`
+	Payments      []Payment       `json:"payments"`
+}
+
+//  overpayingPayments returns the payments made on or after the day the debt's balance first went
+//  below zero. Payments made the same day are listed together, since it takes all of them to overpay
+func (debt *Debt) overpayingPayments() []Payment {
+	var rvalue []Payment
+
+	if debt.paymentPlan == nil {
+		return rvalue
+	}
+
+	payments := append([]Payment(nil), debt.paymentPlan.payments...)
+	sort.SliceStable(payments, func(i, j int) bool { return payments[i].date.Before(payments[j].date) })
+
+	for idx, pmt := range payments {
+		if debt.remainingAmountAsOf(pmt.date).IsNegative() {
+			for idx > 0 && payments[idx-1].date == pmt.date {
+				idx--
+			}
+			return payments[idx:]
+		}
+	}
+	return rvalue
+}
+
+//  buildRefundsReport lists the debts with a credit balance, in debt id order
+func buildRefundsReport(debts map[int]Debt) []RefundDue {
+	rvalue := make([]RefundDue, 0)
+
+	for _, debt := range debts {
+		if !debt.CreditBalance.IsPositive() {
+			continue
+		}
+		rvalue = append(rvalue, RefundDue{
+			DebtID:        debt.ID,
+			Currency:      debt.currency(),
+			CreditBalance: debt.CreditBalance,
+			Payments:      debt.overpayingPayments(),
+		})
+	}
+
+	sort.Slice(rvalue, func(i, j int) bool { return rvalue[i].DebtID < rvalue[j].DebtID })
+	return rvalue
+}
@@ -0,0 +1,49 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDebt_overpayingPayments(t *testing.T) {
+	plan := testPlan()
+	plan.AmountToPay = decimal.NewFromInt(300)
+	payments := []Payment{
+		{ID: 1, Amount: decimal.NewFromInt(100), Date: "2021-01-04"},
+		{ID: 2, Amount: decimal.NewFromInt(150), Date: "2021-01-11"},
+		{ID: 3, Amount: decimal.NewFromInt(40), Date: "2021-01-18"},
+		{ID: 4, Amount: decimal.NewFromInt(20), Date: "2021-01-18"},
+		{ID: 5, Amount: decimal.NewFromInt(100), Date: "2021-01-25"},
+	}
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(300)}, plan, payments, "2021-12-31")
+
+	t.Logf("Checking the remaining amount stops at zero and the rest is credit")
+	if remaining, err := debt.calculateRemainingAmount(true); err != nil || !remaining.IsZero() || !debt.CreditBalance.Equal(decimal.NewFromInt(110)) {
+		t.Errorf("calculateRemainingAmount(), want:0 with 110 credit, got:%v with %v credit", remaining, debt.CreditBalance)
+	}
+
+	//  The balance goes negative on 2021-01-18, which takes both of that day's payments
+	t.Logf("Checking the payments that overpaid")
+	got := debt.overpayingPayments()
+	if len(got) != 3 || got[0].ID != 3 || got[1].ID != 4 || got[2].ID != 5 {
+		t.Errorf("overpayingPayments(), want:payments 3, 4 and 5, got:%v", got)
+	}
+}
+
+func TestBuildRefundsReport(t *testing.T) {
+	var debts map[int]Debt
+
+	err := makeMockGraph(&debts)
+	if err != nil {
+		t.Fatalf("buildRefundsReport(), error making mock data: %v", err)
+	}
+
+	got := buildRefundsReport(debts)
+	if len(got) != 1 || got[0].DebtID != 9 || !got[0].CreditBalance.Equal(decimal.NewFromInt(100)) || got[0].Currency != defaultCurrency {
+		t.Fatalf("buildRefundsReport(), want:debt 9 with 100 USD due, got:%v", got)
+	}
+	if len(got[0].Payments) == 0 {
+		t.Errorf("buildRefundsReport() expected the payments that overpaid debt 9")
+	}
+}
@@ -0,0 +1,121 @@
+package accord
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+//  Installment is one scheduled payment of a payment plan
+type Installment struct {
+	Sequence        int             `json:"sequence"`          //  1-based position in the schedule
+	DueDate         CivilDate       `json:"due_date"`          //  Due date, after rolling off weekends and holidays
+	AmountDue       decimal.Decimal `json:"amount_due"`        //  installment_amount, except for the final installment
+	ExpectedBalance decimal.Decimal `json:"expected_balance"`  //  What should be left to pay once this installment is paid
+	AmountPaid      decimal.Decimal `json:"amount_paid"`       //  How much of AmountDue the principal paid has covered
+	Status          string          `json:"status"`            //  paid, partial or unpaid
+	PaidOn          *CivilDate      `json:"paid_on,omitempty"` //  Date of the payment that finished paying it
+}
+
+//  Schedule is a payment plan's installments in due date order
+type Schedule []Installment
+
+//  generatePaymentSchedule lays out the plan's installments from its start date and frequency.
+//  Installments are installment_amount each until the last one, which is exactly what is left of
+//  amount_to_pay, and each is due on the date the frequency puts it on, rolled off weekends and
+//  holidays. A plan without a positive installment amount would never be paid down, so it gets no
+//  schedule at all. Payments don't have to land on a due date to count: allocatePayments credits the
+//  principal they paid to the oldest installments first however they were timed, and the match
+//  policy decides how late an installment can be paid and still be on time
+func (plan *PaymentPlan) generatePaymentSchedule() {
+	var err error = nil
+
+	plan.schedule = nil
+
+	recurrence, err := parseRecurrence(plan.InstallmentFrequency)
+
+	if err == nil && plan.InstallmentAmount.IsPositive() {
+		anticipatedDebtAmount := plan.resolveAmountToPay(plan.debtAmount)
+		plan.schedule = make([]Installment, 0, anticipatedDebtAmount.Div(plan.InstallmentAmount).Ceil().IntPart())
+
+		for n := 0; anticipatedDebtAmount.IsPositive(); n++ {
+			amountDue := plan.InstallmentAmount
+			if amountDue.GreaterThan(anticipatedDebtAmount) {
+				amountDue = anticipatedDebtAmount
+			}
+			anticipatedDebtAmount = anticipatedDebtAmount.Sub(amountDue)
+
+			plan.schedule = append(plan.schedule, Installment{
+				Sequence:        n + 1,
+				DueDate:         plan.dueDate(recurrence, n),
+				AmountDue:       amountDue,
+				ExpectedBalance: anticipatedDebtAmount,
+				AmountPaid:      decimal.Zero,
+				Status:          installmentUnpaid,
+			})
+		}
+	}
+}
+
+//  dueDate returns the n-th installment's due date: the date the plan's frequency puts it on,
+//  rolled off weekends and holidays
+func (plan *PaymentPlan) dueDate(recurrence Recurrence, n int) CivilDate {
+	return plan.currentSettings().roll.adjust(recurrence.occurrence(plan.startDate, n))
+}
+
+//  nextDueDateAfter returns the first due date strictly after date. Rolling can move a due date
+//  either side of the date the frequency puts it on, so we walk the rolled dates into place
+//  rather than trusting the unrolled calendar
+func (plan *PaymentPlan) nextDueDateAfter(recurrence Recurrence, date CivilDate) CivilDate {
+	n := recurrence.indexOnOrBefore(plan.startDate, date)
+	if n < 0 {
+		n = 0
+	}
+	for n > 0 && plan.dueDate(recurrence, n-1).After(date) {
+		n--
+	}
+	for !plan.dueDate(recurrence, n).After(date) {
+		n++
+	}
+	return plan.dueDate(recurrence, n)
+}
+
+//  firstInstallmentOnOrAfter returns the index of the first installment due on or after date,
+//  or len(plan.schedule) when there isn't one. Rolled due dates never go backwards, so the
+//  schedule can be binary searched
+func (plan *PaymentPlan) firstInstallmentOnOrAfter(date CivilDate) int {
+	return sort.Search(len(plan.schedule), func(i int) bool { return !plan.schedule[i].DueDate.Before(date) })
+}
+
+//  installmentDueOn returns the installment due on date, if there is one
+func (plan *PaymentPlan) installmentDueOn(date CivilDate) (Installment, bool) {
+	idx := plan.firstInstallmentOnOrAfter(date)
+	if idx < len(plan.schedule) && plan.schedule[idx].DueDate == date {
+		return plan.schedule[idx], true
+	}
+	return Installment{}, false
+}
+
+//  isPaymentDateAScheduledDate is used to see if a specified date is in the schedule
+func (plan *PaymentPlan) isPaymentDateAScheduledDate(paymentDate CivilDate) bool {
+	_, rc := plan.installmentDueOn(paymentDate)
+	return rc
+}
+
+//  NextDue is a copy of up to count installments due strictly after date
+func (schedule Schedule) NextDue(date CivilDate, count int) Schedule {
+	idx := sort.Search(len(schedule), func(i int) bool { return schedule[i].DueDate.After(date) })
+	end := idx + count
+	if end > len(schedule) {
+		end = len(schedule)
+	}
+	if idx >= end {
+		return nil
+	}
+	return append(Schedule(nil), schedule[idx:end]...)
+}
+
+//  NextDueInstallments is a copy of up to count of the plan's installments due strictly after date
+func (plan *PaymentPlan) NextDueInstallments(date CivilDate, count int) Schedule {
+	return plan.schedule.NextDue(date, count)
+}
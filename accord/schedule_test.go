@@ -0,0 +1,80 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPaymentPlan_generatePaymentSchedule(t *testing.T) {
+	var debts map[int]Debt
+
+	err := makeMockGraph(&debts)
+	if err != nil {
+		t.Fatalf("generatePaymentSchedule(), error making mock data: %v", err)
+	}
+
+	t.Logf("Checking the final installment is the exact remaining balance")
+	plan := debts[4].paymentPlan
+	if len(plan.schedule) != 24 {
+		t.Fatalf("generatePaymentSchedule(), want:24 installments, got:%v", len(plan.schedule))
+	}
+	last := plan.schedule[len(plan.schedule)-1]
+	want := decimal.RequireFromString("2.02")
+	if !last.AmountDue.Equal(want) {
+		t.Errorf("generatePaymentSchedule() final installment, want:%v, got:%v", want, last.AmountDue)
+	}
+	if !last.ExpectedBalance.IsZero() {
+		t.Errorf("generatePaymentSchedule() final expected balance, want:0, got:%v", last.ExpectedBalance)
+	}
+
+	t.Logf("Checking installments are in order, numbered and add up to amount_to_pay")
+	total := decimal.Zero
+	for idx, installment := range plan.schedule {
+		if installment.Sequence != idx+1 {
+			t.Errorf("generatePaymentSchedule() installment %v has sequence %v", idx, installment.Sequence)
+		}
+		if idx > 0 && !installment.DueDate.After(plan.schedule[idx-1].DueDate) {
+			t.Errorf("generatePaymentSchedule() installment %v due %v isn't after %v", idx, installment.DueDate, plan.schedule[idx-1].DueDate)
+		}
+		total = total.Add(installment.AmountDue)
+	}
+	if !total.Equal(plan.AmountToPay) {
+		t.Errorf("generatePaymentSchedule() installments total, want:%v, got:%v", plan.AmountToPay, total)
+	}
+
+	t.Logf("Checking a plan without a positive installment amount gets no schedule")
+	zeroPlan := PaymentPlan{AmountToPay: decimal.NewFromInt(100), InstallmentFrequency: "weekly", InstallmentAmount: decimal.Zero, startDate: mustParseDate(t, "2021-01-04")}
+	zeroPlan.generatePaymentSchedule()
+	if len(zeroPlan.schedule) != 0 {
+		t.Errorf("generatePaymentSchedule(), want no installments, got:%v", len(zeroPlan.schedule))
+	}
+}
+
+func TestPaymentPlan_NextDueInstallments(t *testing.T) {
+	plan := PaymentPlan{AmountToPay: decimal.NewFromInt(250), InstallmentFrequency: "monthly", InstallmentAmount: decimal.NewFromInt(100), startDate: mustParseDate(t, "2021-01-31")}
+	plan.generatePaymentSchedule()
+
+	t.Logf("Checking the next two installments after a due date")
+	got := plan.NextDueInstallments(mustParseDate(t, "2021-01-31"), 2)
+	if len(got) != 2 {
+		t.Fatalf("NextDueInstallments(), want:2 installments, got:%v", len(got))
+	}
+	if got[0].DueDate.String() != "2021-02-28" || got[1].DueDate.String() != "2021-03-31" {
+		t.Errorf("NextDueInstallments(), want:2021-02-28 and 2021-03-31, got:%v and %v", got[0].DueDate, got[1].DueDate)
+	}
+	if !got[1].AmountDue.Equal(decimal.NewFromInt(50)) {
+		t.Errorf("NextDueInstallments() final installment, want:50, got:%v", got[1].AmountDue)
+	}
+
+	t.Logf("Checking the installments are a copy")
+	got[0].AmountDue = decimal.Zero
+	if !plan.schedule[1].AmountDue.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("NextDueInstallments(), want:a copy, got:the plan's schedule")
+	}
+
+	t.Logf("Checking there is nothing due after the last installment")
+	if got = plan.NextDueInstallments(mustParseDate(t, "2021-03-31"), 5); len(got) != 0 {
+		t.Errorf("NextDueInstallments(), want no installments, got:%v", len(got))
+	}
+}
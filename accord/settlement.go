@@ -0,0 +1,126 @@
+package accord
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+//  ForgivenAmount is the part of a settled debt that was cancelled once the plan completed, with what's
+//  needed to report the cancellation of debt
+type ForgivenAmount struct {
+	DebtID         int             `json:"debt_id"`
+	Currency       string          `json:"currency"`
+	OriginalAmount decimal.Decimal `json:"original_amount"`
+	AmountPaid     decimal.Decimal `json:"amount_paid"`
+	AmountForgiven decimal.Decimal `json:"amount_forgiven"`
+	DateForgiven   CivilDate       `json:"date_forgiven"` //  The day the plan completed
+}
+
+//  SettlementSummary totals the settlements across the portfolio for one currency
+type SettlementSummary struct {
+	Currency              string           `json:"currency"`
+	Debts                 int              `json:"debts"`
+	SettledDebts          int              `json:"settled_debts"` //  Debts on a plan for less than they owe
+	OriginalAmount        decimal.Decimal  `json:"original_amount"`
+	SettledAmount         decimal.Decimal  `json:"settled_amount"`
+	SettlementDiscount    decimal.Decimal  `json:"settlement_discount"`
+	SettlementPercent     decimal.Decimal  `json:"settlement_percent"` //  SettledAmount as a percentage of OriginalAmount, for the settled debts
+	ForgivenDebts         int              `json:"forgiven_debts"`
+	AmountForgiven        decimal.Decimal  `json:"amount_forgiven"`
+	ForgivenAmountRecords []ForgivenAmount `json:"forgiven_amount_records"`
+}
+
+//  isSettlement is true when the debt is on a plan to pay less than it owes
+func (debt *Debt) isSettlement() bool {
+	if debt.paymentPlan == nil {
+		return false
+	}
+	return debt.amountToPay().LessThan(debt.Amount)
+}
+
+//  settlementPercent is what the plan settles for as a percentage of the debt's amount
+func settlementPercent(settledAmount decimal.Decimal, originalAmount decimal.Decimal) decimal.Decimal {
+	if !originalAmount.IsPositive() {
+		return decimal.Zero
+	}
+	return settledAmount.Mul(decimal.NewFromInt(100)).DivRound(originalAmount, 2)
+}
+
+//  calculateSettlement works out the discount a settled plan gives and, once the plan completes, the
+//  amount forgiven. Returns nil for a debt that isn't settled for less than it owes
+func (debt *Debt) calculateSettlement(updateObject bool) *ForgivenAmount {
+	if !debt.isSettlement() {
+		return nil
+	}
+
+	discount := roundAmount(debt.Amount.Sub(debt.amountToPay()), debt.currency(), debt.currentSettings().rounding)
+	percent := settlementPercent(debt.amountToPay(), debt.Amount)
+
+	//  The debt is forgiven on the day the plan last completed
+	var rvalue *ForgivenAmount
+	if completedOn, ok := debt.completedOn(); ok {
+		amountPaid, _, _ := debt.sumTotalPayments()
+		rvalue = &ForgivenAmount{
+			DebtID:         debt.ID,
+			Currency:       debt.currency(),
+			OriginalAmount: debt.Amount,
+			AmountPaid:     amountPaid.Amount,
+			AmountForgiven: discount,
+			DateForgiven:   completedOn,
+		}
+	}
+
+	if updateObject {
+		debt.SettlementDiscount = &discount
+		debt.SettlementPercent = &percent
+		debt.Forgiven = rvalue
+	}
+	return rvalue
+}
+
+//  buildSettlementSummary totals up the settlements across the debts, one summary per currency
+func buildSettlementSummary(debts map[int]Debt) []SettlementSummary {
+	byCurrency := make(map[string]*SettlementSummary)
+
+	for _, id := range sortedDebtIDs(debts) {
+		debt := debts[id]
+
+		summary, ok := byCurrency[debt.currency()]
+		if !ok {
+			summary = &SettlementSummary{
+				Currency:              debt.currency(),
+				OriginalAmount:        decimal.Zero,
+				SettledAmount:         decimal.Zero,
+				SettlementDiscount:    decimal.Zero,
+				SettlementPercent:     decimal.Zero,
+				AmountForgiven:        decimal.Zero,
+				ForgivenAmountRecords: make([]ForgivenAmount, 0),
+			}
+			byCurrency[debt.currency()] = summary
+		}
+		summary.Debts++
+
+		if !debt.isSettlement() {
+			continue
+		}
+		summary.SettledDebts++
+		summary.OriginalAmount = summary.OriginalAmount.Add(debt.Amount)
+		summary.SettledAmount = summary.SettledAmount.Add(debt.amountToPay())
+
+		if forgiven := debt.calculateSettlement(false); forgiven != nil {
+			summary.ForgivenDebts++
+			summary.AmountForgiven = summary.AmountForgiven.Add(forgiven.AmountForgiven)
+			summary.ForgivenAmountRecords = append(summary.ForgivenAmountRecords, *forgiven)
+		}
+	}
+
+	rvalue := make([]SettlementSummary, 0, len(byCurrency))
+	for _, summary := range byCurrency {
+		summary.SettlementDiscount = summary.OriginalAmount.Sub(summary.SettledAmount)
+		summary.SettlementPercent = settlementPercent(summary.SettledAmount, summary.OriginalAmount)
+		rvalue = append(rvalue, *summary)
+	}
+	sort.Slice(rvalue, func(i, j int) bool { return rvalue[i].Currency < rvalue[j].Currency })
+	return rvalue
+}
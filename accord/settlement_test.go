@@ -0,0 +1,63 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDebt_calculateSettlement(t *testing.T) {
+	//  A $500 debt settled for $400
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(500)}, testPlan(), []Payment{testPayment("2021-01-04", 100), testPayment("2021-02-01", 300)}, "2021-12-31")
+
+	t.Logf("Checking the discount on a plan that hasn't completed")
+	debt.calculatePlanStatus(mustParseDate(t, "2021-01-31"), true)
+	if got := debt.calculateSettlement(true); got != nil {
+		t.Errorf("calculateSettlement(), want nothing forgiven yet, got:%+v", *got)
+	}
+	if debt.SettlementDiscount == nil || !debt.SettlementDiscount.Equal(decimal.NewFromInt(100)) || !debt.SettlementPercent.Equal(decimal.NewFromInt(80)) {
+		t.Errorf("calculateSettlement(), want a 100 discount settling for 80%%, got:%v %v", debt.SettlementDiscount, debt.SettlementPercent)
+	}
+
+	t.Logf("Checking the amount forgiven once the plan completes")
+	debt.calculatePlanStatus(mustParseDate(t, "2021-03-01"), true)
+	got := debt.calculateSettlement(true)
+	if got == nil || debt.Forgiven != got {
+		t.Fatalf("calculateSettlement() expected a forgiven amount")
+	}
+	if !got.AmountForgiven.Equal(decimal.NewFromInt(100)) || !got.AmountPaid.Equal(decimal.NewFromInt(400)) || got.DateForgiven.String() != "2021-02-01" {
+		t.Errorf("calculateSettlement(), want 100 forgiven on 2021-02-01 after paying 400, got:%+v", *got)
+	}
+
+	t.Logf("Checking a debt paid in full isn't a settlement")
+	debt.Amount = decimal.NewFromInt(400)
+	debt.SettlementDiscount = nil
+	if got = debt.calculateSettlement(true); got != nil || debt.SettlementDiscount != nil {
+		t.Errorf("calculateSettlement(), want nothing for a debt paid in full, got:%v", got)
+	}
+}
+
+func TestBuildSettlementSummary(t *testing.T) {
+	var debts map[int]Debt
+
+	err := makeMockGraph(&debts)
+	if err != nil {
+		t.Fatalf("buildSettlementSummary(), error making mock data: %v", err)
+	}
+
+	got := buildSettlementSummary(debts)
+	if len(got) != 1 || got[0].Currency != defaultCurrency {
+		t.Fatalf("buildSettlementSummary(), want a single USD summary, got:%v", got)
+	}
+
+	summary := got[0]
+	if summary.Debts != len(debts) || summary.SettledDebts != 6 {
+		t.Errorf("buildSettlementSummary(), want %v debts with 6 settled, got:%v with %v settled", len(debts), summary.Debts, summary.SettledDebts)
+	}
+	if want := decimal.RequireFromString("508884.34"); !summary.SettlementDiscount.Equal(want) {
+		t.Errorf("buildSettlementSummary() discount, want:%v, got:%v", want, summary.SettlementDiscount)
+	}
+	if want := decimal.RequireFromString("67.55"); !summary.SettlementPercent.Equal(want) {
+		t.Errorf("buildSettlementSummary() percent, want:%v, got:%v", want, summary.SettlementPercent)
+	}
+}
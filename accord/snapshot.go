@@ -0,0 +1,295 @@
+package accord
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	//  LatestRun can stand in for a run id to mean the most recent run
+	LatestRun string = "latest"
+
+	snapshotExtension string = ".json"
+	summaryExtension  string = ".summary" //  A run's RunSummary, kept next to its snapshot so listing runs doesn't read them all
+	runIDLayout       string = "20060102T150405.000000000Z"
+)
+
+//  RunSnapshot is everything a run fetched and the debts it worked out, so the run can be looked at later
+type RunSnapshot struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	AsOf      CivilDate `json:"as_of"`
+	Inputs    Inputs    `json:"inputs"`
+	Debts     []Debt    `json:"debts"` //  In debt id order
+}
+
+//  RunSummary is what the runs list command says about a run. Save writes one next to each snapshot
+type RunSummary struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	AsOf      CivilDate `json:"as_of"`
+	DebtCount int       `json:"debt_count"`
+}
+
+//  SnapshotStore keeps a run's snapshot as a JSON file, named after the run id, in a directory
+type SnapshotStore struct {
+	dir string
+}
+
+//  OpenSnapshotStore opens the store in dir, creating the directory if it isn't there yet
+func OpenSnapshotStore(dir string) (*SnapshotStore, error) {
+	if len(dir) == 0 {
+		return nil, fmt.Errorf("Received no snapshot store directory")
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create snapshot store %v:%v", dir, err)
+	}
+	return &SnapshotStore{dir: dir}, nil
+}
+
+//  newRunSnapshot makes the snapshot of a run at the given time. Run ids are the UTC timestamp,
+//  so they sort in the order the runs happened
+func newRunSnapshot(timestamp time.Time, asOf CivilDate, inputs Inputs, debts map[int]Debt) RunSnapshot {
+	rvalue := RunSnapshot{
+		ID:        timestamp.UTC().Format(runIDLayout),
+		Timestamp: timestamp,
+		AsOf:      asOf,
+		Inputs:    inputs,
+		Debts:     make([]Debt, 0, len(debts)),
+	}
+
+	for _, id := range sortedDebtIDs(debts) {
+		rvalue.Debts = append(rvalue.Debts, debts[id])
+	}
+	return rvalue
+}
+
+//  summary is what the runs list command says about the run
+func (snapshot *RunSnapshot) summary() RunSummary {
+	return RunSummary{ID: snapshot.ID, Timestamp: snapshot.Timestamp, AsOf: snapshot.AsOf, DebtCount: len(snapshot.Debts)}
+}
+
+//  path is where the run with the given id is kept. Ids that could escape the store's directory are refused
+func (store *SnapshotStore) path(id string) (string, error) {
+	if len(id) == 0 || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("Received invalid run id %v", id)
+	}
+	return filepath.Join(store.dir, id+snapshotExtension), nil
+}
+
+//  summaryPath is where the summary of the run with the given id is kept
+func (store *SnapshotStore) summaryPath(id string) (string, error) {
+	path, err := store.path(id)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(path, snapshotExtension) + summaryExtension, nil
+}
+
+//  writeJSON writes value to a temporary file and renames it into place, so a run that dies
+//  part way through never leaves half a file behind
+func writeJSON(path string, value interface{}) error {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	temp := path + ".tmp"
+	err = ioutil.WriteFile(temp, bytes, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+//  Save writes the snapshot and then its summary. The summary goes last, so a run only lists once
+//  its snapshot is safely in place
+func (store *SnapshotStore) Save(snapshot RunSnapshot) error {
+	path, err := store.path(snapshot.ID)
+	if err != nil {
+		return err
+	}
+	summaryPath, err := store.summaryPath(snapshot.ID)
+	if err != nil {
+		return err
+	}
+
+	err = writeJSON(path, snapshot)
+	if err != nil {
+		return fmt.Errorf("Unable to write run %v:%v", snapshot.ID, err)
+	}
+
+	err = writeJSON(summaryPath, snapshot.summary())
+	if err != nil {
+		return fmt.Errorf("Unable to write the summary of run %v:%v", snapshot.ID, err)
+	}
+	return nil
+}
+
+//  runIDs lists the ids of the saved runs, oldest first
+func (store *SnapshotStore) runIDs() ([]string, error) {
+	entries, err := ioutil.ReadDir(store.dir)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read snapshot store %v:%v", store.dir, err)
+	}
+
+	rvalue := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, snapshotExtension) {
+			continue
+		}
+		rvalue = append(rvalue, strings.TrimSuffix(name, snapshotExtension))
+	}
+	sort.Strings(rvalue)
+	return rvalue, nil
+}
+
+//  Load reads a saved run. LatestRun loads the most recent one
+func (store *SnapshotStore) Load(id string) (RunSnapshot, error) {
+	var rvalue RunSnapshot
+
+	if id == LatestRun {
+		ids, err := store.runIDs()
+		if err != nil {
+			return rvalue, err
+		}
+		if len(ids) == 0 {
+			return rvalue, fmt.Errorf("No runs saved in %v", store.dir)
+		}
+		id = ids[len(ids)-1]
+	}
+
+	path, err := store.path(id)
+	if err != nil {
+		return rvalue, err
+	}
+
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return rvalue, fmt.Errorf("No run with id %v", id)
+	} else if err != nil {
+		return rvalue, fmt.Errorf("Unable to read run %v:%v", id, err)
+	}
+
+	err = json.Unmarshal(bytes, &rvalue)
+	if err != nil {
+		return rvalue, fmt.Errorf("Unable to parse run %v:%v", id, err)
+	}
+	return rvalue, nil
+}
+
+//  loadSummary reads a run's summary. Runs saved before summaries were written don't have one,
+//  so theirs is worked out from the snapshot instead
+func (store *SnapshotStore) loadSummary(id string) (RunSummary, error) {
+	var rvalue RunSummary
+
+	path, err := store.summaryPath(id)
+	if err != nil {
+		return rvalue, err
+	}
+
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		snapshot, err := store.Load(id)
+		if err != nil {
+			return rvalue, err
+		}
+		return snapshot.summary(), nil
+	} else if err != nil {
+		return rvalue, fmt.Errorf("Unable to read the summary of run %v:%v", id, err)
+	}
+
+	err = json.Unmarshal(bytes, &rvalue)
+	if err != nil {
+		return rvalue, fmt.Errorf("Unable to parse the summary of run %v:%v", id, err)
+	}
+	return rvalue, nil
+}
+
+//  List summarizes every saved run, oldest first, from the runs' summaries
+func (store *SnapshotStore) List() ([]RunSummary, error) {
+	ids, err := store.runIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	rvalue := make([]RunSummary, 0, len(ids))
+	for _, id := range ids {
+		summary, err := store.loadSummary(id)
+		if err != nil {
+			return nil, err
+		}
+		rvalue = append(rvalue, summary)
+	}
+	return rvalue, nil
+}
+
+//  ShowRun is a saved run's snapshot: its inputs and all of its debts
+func (store *SnapshotStore) ShowRun(id string) (RunSnapshot, error) {
+	return store.Load(id)
+}
+
+//  ShowDebt is the debt with debtID as a saved run worked it out
+func (store *SnapshotStore) ShowDebt(id string, debtID int) (Debt, error) {
+	snapshot, err := store.Load(id)
+	if err != nil {
+		return Debt{}, err
+	}
+
+	for _, debt := range snapshot.Debts {
+		if debt.ID == debtID {
+			return debt, nil
+		}
+	}
+	return Debt{}, fmt.Errorf("No debt with id %v in run %v", debtID, snapshot.ID)
+}
+
+//  Prune deletes the runs that aren't among the keep most recent, and those older than cutoff.
+//  A keep of zero or less and a zero cutoff each leave runs alone. It returns the ids it deleted
+func (store *SnapshotStore) Prune(keep int, cutoff time.Time) ([]string, error) {
+	ids, err := store.runIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	rvalue := make([]string, 0)
+	for idx, id := range ids {
+		remove := keep > 0 && idx < len(ids)-keep
+
+		if !remove && !cutoff.IsZero() {
+			timestamp, err := time.Parse(runIDLayout, id)
+			remove = err == nil && timestamp.Before(cutoff)
+		}
+
+		if !remove {
+			continue
+		}
+
+		path, err := store.path(id)
+		if err != nil {
+			return rvalue, err
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return rvalue, fmt.Errorf("Unable to delete run %v:%v", id, err)
+		}
+
+		//  Older runs may not have a summary
+		summaryPath, _ := store.summaryPath(id)
+		err = os.Remove(summaryPath)
+		if err != nil && !os.IsNotExist(err) {
+			return rvalue, fmt.Errorf("Unable to delete the summary of run %v:%v", id, err)
+		}
+		rvalue = append(rvalue, id)
+	}
+	return rvalue, nil
+}
@@ -0,0 +1,100 @@
+package accord
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSnapshotStore(t *testing.T) {
+	store, err := OpenSnapshotStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenSnapshotStore(), want:no error, got:%v", err)
+	}
+
+	debts := map[int]Debt{
+		2: {ID: 2, Amount: decimal.NewFromInt(200), RemainingAmount: decimal.NewFromInt(150)},
+		1: {ID: 1, Amount: decimal.NewFromInt(100), RemainingAmount: decimal.NewFromInt(100)},
+	}
+	inputs := Inputs{Debts: []Debt{debts[1], debts[2]}, Payments: []Payment{{Amount: decimal.NewFromInt(50), Date: "2021-01-04", PaymentPlanID: 1}}}
+
+	asOf := CivilDate{Year: 2021, Month: time.January, Day: 11}
+	first := time.Date(2021, time.January, 4, 9, 0, 0, 0, time.UTC)
+	for days := 0; days < 3; days++ {
+		err = store.Save(newRunSnapshot(first.AddDate(0, 0, days), asOf, inputs, debts))
+		if err != nil {
+			t.Fatalf("Save(), want:no error, got:%v", err)
+		}
+	}
+
+	t.Logf("Checking the runs are listed oldest first")
+	runs, err := store.List()
+	if err != nil || len(runs) != 3 || !runs[0].Timestamp.Equal(first) || runs[0].DebtCount != 2 {
+		t.Fatalf("List(), want:3 runs from %v, got:%v %v", first, runs, err)
+	}
+
+	t.Logf("Checking the latest run loads with its inputs and debts")
+	snapshot, err := store.Load(LatestRun)
+	if err != nil || snapshot.ID != runs[2].ID || snapshot.AsOf != asOf || len(snapshot.Inputs.Payments) != 1 {
+		t.Errorf("Load(), want:%v, got:%v %v", runs[2].ID, snapshot.ID, err)
+	}
+	if len(snapshot.Debts) != 2 || snapshot.Debts[0].ID != 1 {
+		t.Errorf("Load() debts, want:debts 1 and 2, got:%v", snapshot.Debts)
+	}
+
+	t.Logf("Checking a single debt from a run")
+	debt, err := store.ShowDebt(runs[0].ID, 2)
+	if err != nil || !debt.RemainingAmount.Equal(decimal.NewFromInt(150)) {
+		t.Errorf("ShowDebt(), want:150, got:%v %v", debt.RemainingAmount, err)
+	}
+	if _, err = store.ShowDebt(runs[0].ID, 3); err == nil {
+		t.Errorf("ShowDebt(), want:an error for a missing debt, got:none")
+	}
+	if run, err := store.ShowRun(runs[0].ID); err != nil || run.ID != runs[0].ID || len(run.Debts) != 2 {
+		t.Errorf("ShowRun(), want:%v with 2 debts, got:%v %v", runs[0].ID, run.ID, err)
+	}
+
+	t.Logf("Checking runs are listed from their summaries")
+	summaryPath, _ := store.summaryPath(runs[1].ID)
+	err = ioutil.WriteFile(summaryPath, []byte(`{"id":"`+runs[1].ID+`","debt_count":7}`), 0644)
+	if err != nil {
+		t.Fatalf("WriteFile(), want:no error, got:%v", err)
+	}
+	if listed, err := store.List(); err != nil || len(listed) != 3 || listed[1].DebtCount != 7 {
+		t.Errorf("List(), want:the summary's debt count of 7, got:%v %v", listed, err)
+	}
+
+	t.Logf("Checking a run without a summary is still listed")
+	os.Remove(summaryPath)
+	if listed, err := store.List(); err != nil || len(listed) != 3 || listed[1].DebtCount != 2 {
+		t.Errorf("List(), want:a debt count of 2 from the snapshot, got:%v %v", listed, err)
+	}
+
+	t.Logf("Checking run ids can't leave the store")
+	if _, err = store.Load("../" + runs[0].ID); err == nil {
+		t.Errorf("Load(), want:an error, got:none")
+	}
+
+	t.Logf("Checking runs older than a cutoff are pruned")
+	pruned, err := store.Prune(0, first.AddDate(0, 0, 1))
+	if err != nil || len(pruned) != 1 || pruned[0] != runs[0].ID {
+		t.Errorf("Prune(), want:%v, got:%v %v", runs[0].ID, pruned, err)
+	}
+
+	t.Logf("Checking only the most recent runs are kept")
+	pruned, err = store.Prune(1, time.Time{})
+	if err != nil || len(pruned) != 1 || pruned[0] != runs[1].ID {
+		t.Errorf("Prune(), want:%v, got:%v %v", runs[1].ID, pruned, err)
+	}
+	runs, err = store.List()
+	if err != nil || len(runs) != 1 || runs[0].ID != snapshot.ID {
+		t.Errorf("List(), want:%v, got:%v %v", snapshot.ID, runs, err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(store.dir, "*")); len(files) != 2 {
+		t.Errorf("Prune(), want:the last run's snapshot and summary left, got:%v", files)
+	}
+}
@@ -0,0 +1,215 @@
+package accord
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	timelinePlanStart string = "plan_start"
+	timelineDueDate   string = "due_date"
+)
+
+//  TimelineEntry is something that happened to a debt's balance and where it left the balance
+type TimelineEntry struct {
+	Date               CivilDate       `json:"date"`
+	Type               string          `json:"type"`                  //  plan_start, due_date, or the payment's type
+	Amount             decimal.Decimal `json:"amount"`                //  What the plan asks for, what was due, or what was paid or taken back
+	Installment        int             `json:"installment,omitempty"` //  For due dates
+	Status             string          `json:"status,omitempty"`      //  For payments
+	Scheduled          *bool           `json:"scheduled,omitempty"`   //  For payments: whether it was made on a scheduled date
+	RemainingAmount    decimal.Decimal `json:"remaining_amount"`
+	NextPaymentDueDate *string         `json:"next_payment_due_date"`
+}
+
+//  Timeline is how a debt's balance evolved, oldest first
+type Timeline struct {
+	DebtID   int             `json:"debt_id"`
+	Currency string          `json:"currency"`
+	Entries  []TimelineEntry `json:"entries"`
+}
+
+//  buildTimeline lists the plan's start, each payment record and each due date up to the date the debt
+//  was evaluated as of. Each entry carries the remaining amount and next due date it left the debt with.
+//  The debt's events are applied one at a time to a running copy of the debt, and each entry evaluates
+//  what has been applied so far, so payments made on the same day each get their own running balance.
+//  Due dates come after any payments made that day. Every evaluation goes through all the payments up
+//  to its entry, so a timeline costs time in proportion to the square of the debt's events
+func (debt *Debt) buildTimeline() (Timeline, error) {
+	rvalue := Timeline{DebtID: debt.ID, Currency: debt.currency(), Entries: make([]TimelineEntry, 0)}
+
+	plan := debt.paymentPlan
+	if plan == nil {
+		return rvalue, nil
+	}
+
+	asOf := debt.evaluatedAsOf()
+	events := debt.debtEvents()
+
+	//  state holds the events applied so far. Each entry evaluates a copy of it with its own copy of the
+	//  plan, so state is never evaluated itself and every entry's figures come from the events alone
+	var state Debt
+	entryFor := func(date CivilDate, evaluateAsOf CivilDate, entryType string, amount decimal.Decimal) (TimelineEntry, error) {
+		step := state
+		if state.paymentPlan != nil {
+			plan := state.paymentPlan.clone()
+			step.paymentPlan = &plan
+		}
+		err := step.evaluate(evaluateAsOf)
+		return TimelineEntry{Date: date, Type: entryType, Amount: amount, RemainingAmount: step.RemainingAmount, NextPaymentDueDate: step.NextPaymentDate}, err
+	}
+
+	//  addDueDatesBefore adds the due dates up to the day before the given date. Each takes the state
+	//  left by every event up to and including its own day
+	dueIdx := 0
+	addDueDatesBefore := func(date CivilDate) error {
+		for ; dueIdx < len(plan.schedule); dueIdx++ {
+			installment := plan.schedule[dueIdx]
+			if installment.DueDate.After(asOf) || !installment.DueDate.Before(date) {
+				break
+			}
+			entry, err := entryFor(installment.DueDate, installment.DueDate, timelineDueDate, installment.AmountDue)
+			if err != nil {
+				return err
+			}
+			entry.Installment = installment.Sequence
+			rvalue.Entries = append(rvalue.Entries, entry)
+		}
+		return nil
+	}
+
+	for _, event := range events {
+		if state.paymentPlan != nil {
+			err := addDueDatesBefore(event.Date)
+			if err != nil {
+				return rvalue, err
+			}
+		}
+		state.applyEvent(event)
+
+		//  The plan starts out owing all of amount_to_pay, whatever gets paid later that day
+		if event.Type == eventPlanCreated && !plan.startDate.After(asOf) {
+			entry, err := entryFor(plan.startDate, event.Date, timelinePlanStart, debt.amountToPay())
+			if err != nil {
+				return rvalue, err
+			}
+			rvalue.Entries = append(rvalue.Entries, entry)
+		}
+		if event.Payment == nil {
+			continue
+		}
+		pmt := event.Payment
+
+		entry, err := entryFor(pmt.date, event.Date, pmt.paymentType(), pmt.Amount)
+		if err != nil {
+			return rvalue, err
+		}
+		entry.Status = pmt.status()
+		if pmt.paymentType() == paymentTypePayment {
+			_, scheduled := plan.matchScheduledDate(*pmt)
+			entry.Scheduled = &scheduled
+		}
+		rvalue.Entries = append(rvalue.Entries, entry)
+	}
+	if state.paymentPlan != nil {
+		err := addDueDatesBefore(asOf.addDays(1))
+		if err != nil {
+			return rvalue, err
+		}
+	}
+
+	rank := func(entry TimelineEntry) int {
+		switch entry.Type {
+		case timelinePlanStart:
+			return 0
+		case timelineDueDate:
+			return 2
+		}
+		return 1
+	}
+	sort.SliceStable(rvalue.Entries, func(i, j int) bool {
+		if rvalue.Entries[i].Date != rvalue.Entries[j].Date {
+			return rvalue.Entries[i].Date.Before(rvalue.Entries[j].Date)
+		}
+		return rank(rvalue.Entries[i]) < rank(rvalue.Entries[j])
+	})
+	return rvalue, nil
+}
+
+//  buildTimelines makes the timeline of one debt, or of every debt in debt id order when debtID is negative
+func buildTimelines(debts map[int]Debt, debtID int) ([]Timeline, error) {
+	rvalue := make([]Timeline, 0)
+
+	if debtID >= 0 {
+		debt, ok := debts[debtID]
+		if !ok {
+			return nil, fmt.Errorf("No debt with id %v", debtID)
+		}
+		timeline, err := debt.buildTimeline()
+		if err != nil {
+			return nil, err
+		}
+		return append(rvalue, timeline), nil
+	}
+
+	for _, id := range sortedDebtIDs(debts) {
+		debt := debts[id]
+		timeline, err := debt.buildTimeline()
+		if err != nil {
+			return nil, err
+		}
+		rvalue = append(rvalue, timeline)
+	}
+	return rvalue, nil
+}
+
+//  WriteTimelinesCSV writes timelines as CSV, one row per entry
+func WriteTimelinesCSV(w io.Writer, timelines []Timeline) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"debt_id", "currency", "date", "type", "amount", "installment", "status", "scheduled", "remaining_amount", "next_payment_due_date"})
+	if err != nil {
+		return err
+	}
+
+	for _, timeline := range timelines {
+		for _, entry := range timeline.Entries {
+			installment := ""
+			if entry.Installment > 0 {
+				installment = strconv.Itoa(entry.Installment)
+			}
+			scheduled := ""
+			if entry.Scheduled != nil {
+				scheduled = strconv.FormatBool(*entry.Scheduled)
+			}
+			nextDueDate := ""
+			if entry.NextPaymentDueDate != nil {
+				nextDueDate = *entry.NextPaymentDueDate
+			}
+
+			err = writer.Write([]string{
+				strconv.Itoa(timeline.DebtID),
+				timeline.Currency,
+				entry.Date.String(),
+				entry.Type,
+				entry.Amount.String(),
+				installment,
+				entry.Status,
+				scheduled,
+				entry.RemainingAmount.String(),
+				nextDueDate,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,117 @@
+package accord
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+//  makeTimelineTestDebt builds a $300 plan of $100 a week from Monday 2021-01-04 with an on-time payment,
+//  a late partial one and one that pays it off
+func makeTimelineTestDebt(t *testing.T) Debt {
+	plan := testPlan()
+	plan.AmountToPay = decimal.NewFromInt(300)
+	payments := []Payment{
+		{ID: 1, Amount: decimal.NewFromInt(100), Date: "2021-01-04"},
+		{ID: 2, Amount: decimal.NewFromInt(50), Date: "2021-01-13"},
+		{ID: 3, Amount: decimal.NewFromInt(150), Date: "2021-01-18"},
+	}
+	return makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(300)}, plan, payments, "2021-02-01")
+}
+
+func TestDebt_buildTimeline(t *testing.T) {
+	debt := makeTimelineTestDebt(t)
+
+	timeline, err := debt.buildTimeline()
+	if err != nil {
+		t.Fatalf("buildTimeline(), want:no error, got:%v", err)
+	}
+
+	tests := []struct {
+		date        string
+		entryType   string
+		remaining   int64
+		scheduled   string
+		nextDueDate string
+	}{
+		{"2021-01-04", timelinePlanStart, 300, "", "2021-01-04"},
+		{"2021-01-04", paymentTypePayment, 200, "true", "2021-01-11"},
+		{"2021-01-04", timelineDueDate, 200, "", "2021-01-11"},
+		{"2021-01-11", timelineDueDate, 200, "", "2021-01-11"},
+		{"2021-01-13", paymentTypePayment, 150, "false", "2021-01-11"},
+		{"2021-01-18", paymentTypePayment, 0, "true", ""},
+		{"2021-01-18", timelineDueDate, 0, "", ""},
+	}
+
+	if len(timeline.Entries) != len(tests) {
+		t.Fatalf("buildTimeline(), want:%v entries, got:%v", len(tests), timeline.Entries)
+	}
+
+	for idx, test := range tests {
+		entry := timeline.Entries[idx]
+		t.Logf("Checking the %v entry on %v", test.entryType, test.date)
+
+		if entry.Date.String() != test.date || entry.Type != test.entryType {
+			t.Errorf("buildTimeline() entry %v, want:%v %v, got:%v %v", idx, test.date, test.entryType, entry.Date, entry.Type)
+		}
+		if !entry.RemainingAmount.Equal(decimal.NewFromInt(test.remaining)) {
+			t.Errorf("buildTimeline() remaining amount, want:%v, got:%v", test.remaining, entry.RemainingAmount)
+		}
+
+		scheduled := ""
+		if entry.Scheduled != nil {
+			if *entry.Scheduled {
+				scheduled = "true"
+			} else {
+				scheduled = "false"
+			}
+		}
+		if scheduled != test.scheduled {
+			t.Errorf("buildTimeline() scheduled, want:%v, got:%v", test.scheduled, scheduled)
+		}
+
+		nextDueDate := ""
+		if entry.NextPaymentDueDate != nil {
+			nextDueDate = *entry.NextPaymentDueDate
+		}
+		if nextDueDate != test.nextDueDate {
+			t.Errorf("buildTimeline() next due date, want:%v, got:%v", test.nextDueDate, nextDueDate)
+		}
+	}
+
+	t.Logf("Checking a debt without a plan")
+	noPlan := Debt{ID: 2, Amount: decimal.NewFromInt(100)}
+	if got, _ := noPlan.buildTimeline(); len(got.Entries) != 0 {
+		t.Errorf("buildTimeline(), want:no entries, got:%v", got.Entries)
+	}
+}
+
+func TestWriteTimelinesCSV(t *testing.T) {
+	debt := makeTimelineTestDebt(t)
+
+	timeline, err := debt.buildTimeline()
+	if err != nil {
+		t.Fatalf("buildTimeline(), want:no error, got:%v", err)
+	}
+
+	var buffer bytes.Buffer
+	err = WriteTimelinesCSV(&buffer, []Timeline{timeline})
+	if err != nil {
+		t.Fatalf("WriteTimelinesCSV(), want:no error, got:%v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+
+	t.Logf("Checking the header and a row per entry")
+	if len(lines) != 8 || !strings.HasPrefix(lines[0], "debt_id,currency,date,type") {
+		t.Fatalf("WriteTimelinesCSV(), want:a header and 7 rows, got:%v", lines)
+	}
+
+	t.Logf("Checking the late partial payment's row")
+	want := "1,USD,2021-01-13,payment,50,,settled,false,150,2021-01-11"
+	if lines[5] != want {
+		t.Errorf("WriteTimelinesCSV(), want:%v, got:%v", want, lines[5])
+	}
+}
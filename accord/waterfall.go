@@ -0,0 +1,227 @@
+package accord
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	componentFees      string = "fees"
+	componentInterest  string = "interest"
+	componentPrincipal string = "principal"
+)
+
+//  debtBalances is what a debt owes split into its parts, along with how each part got there
+type debtBalances struct {
+	currency  string
+	rounding  string
+	interest  InterestBreakdown
+	fees      FeeBreakdown
+	accruals  []interestAccrual //  Each time interest was added to the balance, in date order
+	steps     []balanceStep     //  What was owed after each day a fee or payment landed on, in date order
+	principal []Payment         //  The part of each payment that went to principal, which is what pays installments
+}
+
+//  balanceStep is what was still owed at the end of date. Interest only accrues on a balance that is
+//  still owed, so whether anything is owed doesn't change until the next step
+type balanceStep struct {
+	date      CivilDate
+	remaining decimal.Decimal
+}
+
+//  interestAccrual is interest added to the balance for the period ending on date
+type interestAccrual struct {
+	date   CivilDate
+	amount decimal.Decimal
+}
+
+//  remaining is everything still owed
+func (balances *debtBalances) remaining() decimal.Decimal {
+	rvalue := addAmount(balances.interest.PrincipalOutstanding, balances.interest.InterestOutstanding)
+	return roundAmount(addAmount(rvalue, balances.fees.FeesOutstanding), balances.currency, balances.rounding)
+}
+
+//  parseWaterfall reads a comma separated payment application order, e.g. fees,interest,principal.
+//  Each of fees, interest and principal has to appear exactly once
+func parseWaterfall(value string) ([]string, error) {
+	var rvalue []string
+	seen := make(map[string]bool)
+
+	for _, component := range strings.Split(value, ",") {
+		component = strings.ToLower(strings.TrimSpace(component))
+		switch component {
+		case componentFees, componentInterest, componentPrincipal:
+		default:
+			return nil, fmt.Errorf("Received unexpected balance component %v; expected fees, interest or principal", component)
+		}
+		if seen[component] {
+			return nil, fmt.Errorf("Received balance component %v more than once", component)
+		}
+		seen[component] = true
+		rvalue = append(rvalue, component)
+	}
+
+	if len(rvalue) != 3 {
+		return nil, fmt.Errorf("Expected fees, interest and principal in the waterfall, received %v", value)
+	}
+	return rvalue, nil
+}
+
+//  calculateBalances replays the plan up to asOf. Interest accrues (when the debt has interest terms)
+//  from the plan's start date, fees are charged as they are assessed, and each payment pays off the
+//  parts of the balance in the configured waterfall order. Anything a payment has left over once everything
+//  is paid off overpays principal. Fees assessed on the same day as a payment are charged first.
+//  Installments are paid by principal only, so a late fee is charged when the principal paid by the end
+//  of the installment's grace window doesn't cover it, and fees stop being charged, or are cut short,
+//  once they reach the cap
+func (debt *Debt) calculateBalances(asOf CivilDate) *debtBalances {
+	plan := debt.paymentPlan
+	if plan == nil {
+		return nil
+	}
+
+	terms := debt.InterestTerms
+	config := debt.currentSettings()
+
+	rvalue := &debtBalances{
+		currency: debt.currency(),
+		rounding: config.rounding,
+		interest: InterestBreakdown{
+			InterestAccrued:      decimal.Zero,
+			InterestPaid:         decimal.Zero,
+			PrincipalPaid:        decimal.Zero,
+			InterestOutstanding:  decimal.Zero,
+			PrincipalOutstanding: debt.amountToPay(),
+		},
+		fees: FeeBreakdown{
+			FeesAssessed:    decimal.Zero,
+			FeesPaid:        decimal.Zero,
+			FeesOutstanding: decimal.Zero,
+		},
+	}
+	room, capped := plan.feeCap(newMoney(debt.amountToPay(), debt.Currency))
+
+	//  The payments are copied so each one's amount can be cut down to the part that went to principal
+	payments := make([]Payment, 0, len(plan.payments))
+	for _, pmt := range plan.payments {
+		if !pmt.date.After(asOf) {
+			payments = append(payments, pmt)
+		}
+	}
+	sort.SliceStable(payments, func(i, j int) bool { return payments[i].date.Before(payments[j].date) })
+	rvalue.principal = payments
+
+	accruedThrough := plan.startDate
+	accrueTo := func(date CivilDate) {
+		if terms != nil && date.After(accruedThrough) {
+			interest := terms.accrue(rvalue.interest.PrincipalOutstanding, rvalue.interest.InterestOutstanding, accruedThrough, date, rvalue.currency, rvalue.rounding)
+			rvalue.interest.InterestAccrued = rvalue.interest.InterestAccrued.Add(interest)
+			rvalue.interest.InterestOutstanding = rvalue.interest.InterestOutstanding.Add(interest)
+			if !interest.IsZero() {
+				rvalue.accruals = append(rvalue.accruals, interestAccrual{date: date, amount: interest})
+			}
+			accruedThrough = date
+		}
+	}
+
+	//  pay takes as much of amount as is outstanding, moving it from outstanding to paid. Most debts
+	//  have neither fees nor interest, so nothing is worked out for parts with nothing outstanding
+	pay := func(amount decimal.Decimal, outstanding *decimal.Decimal, paid *decimal.Decimal) decimal.Decimal {
+		if !amount.IsPositive() || !outstanding.IsPositive() {
+			return amount
+		}
+		part := decimal.Min(amount, *outstanding)
+		*paid = paid.Add(part)
+		*outstanding = outstanding.Sub(part)
+		return amount.Sub(part)
+	}
+
+	applyPayment := func(pmt *Payment) {
+		amount := pmt.Amount
+
+		//  With no fees or interest outstanding the whole payment goes to principal
+		if !rvalue.fees.FeesOutstanding.IsPositive() && !rvalue.interest.InterestOutstanding.IsPositive() {
+			rvalue.interest.PrincipalPaid = rvalue.interest.PrincipalPaid.Add(amount)
+			rvalue.interest.PrincipalOutstanding = rvalue.interest.PrincipalOutstanding.Sub(amount)
+			return
+		}
+
+		principalPaid := rvalue.interest.PrincipalPaid
+		for _, component := range config.waterfall {
+			switch component {
+			case componentFees:
+				amount = pay(amount, &rvalue.fees.FeesOutstanding, &rvalue.fees.FeesPaid)
+			case componentInterest:
+				amount = pay(amount, &rvalue.interest.InterestOutstanding, &rvalue.interest.InterestPaid)
+			case componentPrincipal:
+				amount = pay(amount, &rvalue.interest.PrincipalOutstanding, &rvalue.interest.PrincipalPaid)
+			}
+		}
+
+		//  Overpayments come off principal
+		if !amount.IsZero() {
+			rvalue.interest.PrincipalPaid = rvalue.interest.PrincipalPaid.Add(amount)
+			rvalue.interest.PrincipalOutstanding = rvalue.interest.PrincipalOutstanding.Sub(amount)
+		}
+
+		pmt.Amount = rvalue.interest.PrincipalPaid.Sub(principalPaid)
+	}
+
+	fees := plan.feesDue(asOf)
+	rvalue.steps = make([]balanceStep, 0, len(fees)+len(payments))
+	for len(fees) > 0 || len(payments) > 0 {
+		var date CivilDate
+		if len(payments) == 0 || (len(fees) > 0 && !payments[0].date.Before(fees[0].Date)) {
+			fee := fees[0]
+			fees = fees[1:]
+
+			//  An installment is paid once principal is down to what should be left after it
+			if fee.Type == feeTypeLate && !rvalue.interest.PrincipalOutstanding.GreaterThan(plan.schedule[fee.Installment-1].ExpectedBalance) {
+				continue
+			}
+			if capped {
+				if !room.IsPositive() {
+					continue
+				}
+				fee.Amount = decimal.Min(fee.Amount, room)
+				room = room.Sub(fee.Amount)
+			}
+
+			date = fee.Date
+			accrueTo(fee.Date)
+			rvalue.fees.FeesAssessed = rvalue.fees.FeesAssessed.Add(fee.Amount)
+			rvalue.fees.FeesOutstanding = rvalue.fees.FeesOutstanding.Add(fee.Amount)
+			rvalue.fees.Assessments = append(rvalue.fees.Assessments, fee)
+		} else {
+			accrueTo(payments[0].date)
+			applyPayment(&payments[0])
+			date = payments[0].date
+			payments = payments[1:]
+		}
+
+		//  Several fees and payments can land on the same day; only the end of it counts
+		if count := len(rvalue.steps); count > 0 && rvalue.steps[count-1].date == date {
+			rvalue.steps = rvalue.steps[:count-1]
+		}
+		rvalue.steps = append(rvalue.steps, balanceStep{date: date, remaining: rvalue.remaining()})
+	}
+
+	//  Interest keeps running on whatever is left, as long as there's something left
+	if rvalue.interest.PrincipalOutstanding.IsPositive() {
+		accrueTo(asOf)
+	}
+
+	//  Payments that went wholly to fees and interest pay no installment
+	principal := rvalue.principal[:0]
+	for _, pmt := range rvalue.principal {
+		if pmt.Amount.IsPositive() {
+			principal = append(principal, pmt)
+		}
+	}
+	rvalue.principal = principal
+
+	return rvalue
+}
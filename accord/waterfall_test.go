@@ -0,0 +1,71 @@
+package accord
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseWaterfall(t *testing.T) {
+	t.Logf("Checking a reordered waterfall")
+	got, err := parseWaterfall("Principal, fees,interest")
+	if err != nil || len(got) != 3 || got[0] != componentPrincipal || got[1] != componentFees || got[2] != componentInterest {
+		t.Errorf("parseWaterfall(), want:[principal fees interest], got:%v %v", got, err)
+	}
+
+	for _, value := range []string{"fees,interest", "fees,interest,principal,fees", "fees,penalties,principal"} {
+		t.Logf("Checking %v is rejected", value)
+		if _, err = parseWaterfall(value); err == nil {
+			t.Errorf("parseWaterfall(%v) expected an error", value)
+		}
+	}
+}
+
+func TestDebt_calculateBalances(t *testing.T) {
+	//  The second installment is paid a week late and the third not at all, so $50 of late fees
+	//  have been charged by the time the $100 arrives on 2021-01-20
+	payments := []Payment{testPayment("2021-01-04", 100), testPayment("2021-01-20", 100)}
+	asOf := mustParseDate(t, "2021-01-20")
+
+	tests := []struct {
+		description   string
+		waterfall     []string
+		wantFees      string
+		wantPrincipal string
+	}{
+		{"fees first", []string{componentFees, componentInterest, componentPrincipal}, "0", "250"},
+		{"principal first", []string{componentPrincipal, componentFees, componentInterest}, "50", "200"},
+	}
+
+	for _, test := range tests {
+		t.Logf("Checking %v", test.description)
+		config := testSettings()
+		config.waterfall = test.waterfall
+		plan := testPlan()
+		plan.FeeSchedule = &FeeSchedule{LateFee: decimal.NewFromInt(25)}
+		plan.settings = config
+		debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, plan, payments, "2021-12-31")
+		got := debt.calculateBalances(asOf)
+
+		if !got.fees.FeesAssessed.Equal(decimal.NewFromInt(50)) {
+			t.Errorf("calculateBalances() fees assessed, want:50, got:%v", got.fees.FeesAssessed)
+		}
+		if want := decimal.RequireFromString(test.wantFees); !got.fees.FeesOutstanding.Equal(want) {
+			t.Errorf("calculateBalances() fees outstanding, want:%v, got:%v", want, got.fees.FeesOutstanding)
+		}
+		if want := decimal.RequireFromString(test.wantPrincipal); !got.interest.PrincipalOutstanding.Equal(want) {
+			t.Errorf("calculateBalances() principal outstanding, want:%v, got:%v", want, got.interest.PrincipalOutstanding)
+		}
+		if remaining := got.remaining(); !remaining.Equal(decimal.NewFromInt(250)) {
+			t.Errorf("remaining(), want:250, got:%v", remaining)
+		}
+	}
+
+	t.Logf("Checking an overpayment comes off principal")
+	plan := testPlan()
+	plan.FeeSchedule = &FeeSchedule{}
+	debt := makeTestDebt(t, Debt{ID: 1, Amount: decimal.NewFromInt(400)}, plan, []Payment{testPayment("2021-01-04", 450)}, "2021-12-31")
+	if remaining := debt.calculateBalances(asOf).remaining(); !remaining.Equal(decimal.NewFromInt(-50)) {
+		t.Errorf("remaining(), want:-50, got:%v", remaining)
+	}
+}
@@ -2,124 +2,385 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
-	"math"
 	"net/http"
-	"sort"
-	"strings"
+	"os"
 	"time"
+	_ "time/tzdata" //  Embedded so --tz works on machines without a zoneinfo database
 
 	"github.com/shopspring/decimal"
+
+	"true_accord/accord"
 )
 
 const (
 	debtApiServer        string = "https://my-json-server.typicode.com/druska/trueaccord-mock-payments-api/debts"
 	paymentPlanApiServer string = "https://my-json-server.typicode.com/druska/trueaccord-mock-payments-api/payment_plans"
 	paymentsApiServer    string = "https://my-json-server.typicode.com/druska/trueaccord-mock-payments-api/payments"
-	isoDateLayout        string = "2006-01-02"
-	weekly               string = "weekly"
-	biweekly             string = "bi_weekly"
 )
 
-var (
-	gracePeriodDuration time.Duration
+//  With no command we output the debts; the other commands output reports on them instead
+const (
+	commandRefunds     string = "refunds"     //  The refunds due report
+	commandSettlements string = "settlements" //  The portfolio settlement summary
+	commandLedger      string = "ledger"      //  The journal and trial balance
+	commandStatements  string = "statements"  //  Each debt's account statement
+	commandHistory     string = "history"     //  Every debt's event stream
+	commandReplay      string = "replay"      //  A debt rebuilt from its events as of an event or a date
+	commandTimeline    string = "timeline"    //  Each debt's balance timeline
+	commandRuns        string = "runs"        //  The runs saved in the snapshot store: runs list, runs show or runs prune
+	commandDiff        string = "diff"        //  What changed between two runs, or between a run and the services now
+
+	runsList  string = "list"
+	runsShow  string = "show"
+	runsPrune string = "prune"
+
+	formatJSON string = "json"
+	formatCSV  string = "csv"
+	formatText string = "text"
 )
 
-func init() {
-	gracePeriodDuration, _ = time.ParseDuration("120h")
-	//  We want our decimals to be marshalled/unmarshalled without quotes, thank you very much
-	decimal.MarshalJSONWithoutQuotes = true
-}
-
-type Debt struct {
-	ID                        int             `json:"id"`
-	Amount                    decimal.Decimal `json:"amount"`
-	InPaymentPlan             bool            `json:"is_in_payment_plan"`
-	RemainingAmount           decimal.Decimal `json:"remaining_amount"`
-	remainingAmountCalculated bool
-	NextPaymentDate           *string `json:"next_payment_due_date"`
-	paymentPlan               *PaymentPlan
-}
-
-type PaymentPlan struct {
-	ID                   int             `json:"id"`
-	DebtID               int             `json:"debt_id"`
-	AmountToPay          decimal.Decimal `json:"amount_to_pay"`
-	InstallmentFrequency string          `json:"installment_frequency"`
-	InstallmentAmount    decimal.Decimal `json:"installment_amount"`
-	StartDate            string          `json:"start_date"`
-	startDate            time.Time       //  The date converted to golang date format
-	payments             []Payment
-	schedule             map[time.Time]decimal.Decimal //  Key scheduled payment date, value scheduled balance
-}
-
-type Payment struct {
-	Amount        decimal.Decimal `json:"amount"`
-	Date          string          `json:"date"`
-	date          time.Time       //  The date converted to golang date format
-	PaymentPlanID int             `json:"payment_plan_id"`
-	scheduled     bool            //    Flag indicating a payment is scheduled
-}
-
 //  Used to grab results and error codes from the goroutine which
 //  retrieves Debts from the web-service
 type DebtsReturn struct {
-	debts map[int]Debt
+	debts []accord.Debt
 	err   error
 }
 
 //  Used to grab results and error codes from the goroutine which
 //  retrieves PaymentPlans from the web-service
 type PaymentPlansReturn struct {
-	paymentPlans map[int]PaymentPlan
+	paymentPlans []accord.PaymentPlan
 	err          error
 }
 
 //  Used to grab results and error codes from the goroutine which
 //  retrieves Payments from the web-service
 type PaymentsReturn struct {
-	payments []Payment
+	payments []accord.Payment
 	err      error
 }
 
+func init() {
+	//  We want our decimals to be marshalled/unmarshalled without quotes, thank you very much.
+	//  It's a setting for the whole program, so it's made here rather than in the accord package
+	decimal.MarshalJSONWithoutQuotes = true
+}
+
 func main() {
 	var err error = nil
 
-	var debts map[int]Debt
+	var timeZone string
+	var holidayFile string
+	var asOf string
+	var statusFilter string
+	var replayDebtID int
+	var replayEvent int
+	var format string
+	var storeDir string
+	var runID string
+	var keepRuns int
+	var olderThanDays int
+
+	options := accord.DefaultOptions()
+
+	flag.StringVar(&timeZone, "tz", "UTC", "Business time zone (IANA name) used to decide which day a timestamped payment falls on")
+	flag.StringVar(&options.MatchPolicy, "match", options.MatchPolicy, "How payments are matched to scheduled dates: exact, within:N (N days either side) or late:N (up to N days late). An installment paid inside the window is on time for delinquency, plan status and late fees")
+	flag.StringVar(&holidayFile, "holidays", "", "File of bank holidays (YYYY-MM-DD per line) that due dates can't fall on, e.g. holidays/us_federal_reserve.txt")
+	flag.StringVar(&options.Roll, "roll", options.Roll, "How due dates on weekends and holidays move: none, following, modified_following or preceding")
+	flag.StringVar(&asOf, "as-of", "", "Evaluate debts as they stood at the end of this date (YYYY-MM-DD) instead of today; later payments are ignored")
+	flag.IntVar(&options.StatusThresholds.DelinquentAfterDays, "delinquent-after-days", options.StatusThresholds.DelinquentAfterDays, "Days an installment can go unpaid once its --match window is over before a plan is delinquent")
+	flag.IntVar(&options.StatusThresholds.BrokenAfterMissed, "broken-after-missed", options.StatusThresholds.BrokenAfterMissed, "Consecutive missed installments that break a plan")
+	flag.StringVar(&options.Waterfall, "waterfall", options.Waterfall, "The order payments pay off a balance's fees, interest and principal")
+	flag.StringVar(&options.Rounding, "rounding", options.Rounding, "How amounts half way between two minor units are rounded: half_up, or half_even (banker's rounding)")
+	flag.StringVar(&options.ZeroAmountPolicy, "zero-amount-to-pay", options.ZeroAmountPolicy, "What a plan with an amount_to_pay of zero means: full_debt (the debt's amount is owed), forgiven (nothing is owed) or reject")
+	flag.IntVar(&options.Workers, "workers", options.Workers, "How many debts are worked out at once")
+	flag.IntVar(&replayDebtID, "debt", -1, "The debt the replay command rebuilds, or the only debt the timeline and runs show commands output")
+	flag.IntVar(&replayEvent, "at-event", 0, "Have the replay command rebuild the debt as of this event in its history instead of the as-of date")
+	flag.StringVar(&format, "format", formatJSON, "How output is written: json, csv (timeline command only) or text (diff command only)")
+	flag.StringVar(&storeDir, "store", "", "Directory of the snapshot store; when given, each run's inputs and debts are saved there")
+	flag.StringVar(&runID, "run", accord.LatestRun, "The run the runs show command outputs")
+	flag.IntVar(&keepRuns, "keep", 0, "Have the runs prune command keep only this many of the most recent runs")
+	flag.IntVar(&olderThanDays, "older-than", 0, "Have the runs prune command delete runs more than this many days old")
+	flag.StringVar(&statusFilter, "status", "", "Only output debts whose plans are in these statuses (comma separated): pending, active, delinquent, broken, completed, overpaid")
+	flag.Parse()
+
+	//  Checked before the options fill in defaults, which would quietly replace thresholds that are both zero
+	err = options.StatusThresholds.Validate()
 
-	var debtList []Debt
+	if err != nil {
+		fmt.Printf("Error reading --delinquent-after-days and --broken-after-missed:%v", err)
+		return
+	}
 
-	//  Populate the debts structure which includes debts, plans and payments
-	err = populateDebtHierarchy(&debts)
+	options.Location, err = time.LoadLocation(timeZone)
+
+	if err != nil {
+		fmt.Printf("Error loading time zone %v:%v", timeZone, err)
+		return
+	}
+
+	if len(holidayFile) > 0 {
+		options.Holidays, err = accord.LoadHolidayCalendar(holidayFile)
+
+		if err != nil {
+			fmt.Printf("Error loading holiday calendar:%v", err)
+			return
+		}
+	}
+
+	if len(asOf) > 0 {
+		options.AsOf, err = accord.ParseCivilDate(asOf)
+
+		if err != nil {
+			fmt.Printf("Error reading as-of date:%v", err)
+			return
+		}
+	}
+
+	err = options.Validate()
+
+	if err != nil {
+		fmt.Printf("Error reading options:%v", err)
+		return
+	}
+
+	statuses, err := accord.ParseStatusFilter(statusFilter)
+
+	if err != nil {
+		fmt.Printf("Error reading status filter:%v", err)
+		return
+	}
+
+	command := flag.Arg(0)
+
+	switch command {
+	case "", commandRefunds, commandSettlements, commandLedger, commandStatements, commandHistory, commandReplay, commandTimeline, commandRuns, commandDiff:
+	default:
+		fmt.Printf("Unknown command %v; expected %v, %v, %v, %v, %v, %v, %v, %v, %v or nothing", command,
+			commandRefunds, commandSettlements, commandLedger, commandStatements, commandHistory, commandReplay, commandTimeline, commandRuns, commandDiff)
+		return
+	}
+
+	switch format {
+	case formatJSON:
+	case formatCSV:
+		if command != commandTimeline {
+			fmt.Printf("Error reading format:only the %v command can output %v", commandTimeline, formatCSV)
+			return
+		}
+	case formatText:
+		if command != commandDiff {
+			fmt.Printf("Error reading format:only the %v command can output %v", commandDiff, formatText)
+			return
+		}
+	default:
+		fmt.Printf("Error reading format:received %v; expected %v, %v or %v", format, formatJSON, formatCSV, formatText)
+		return
+	}
+
+	var store *accord.SnapshotStore
+
+	if len(storeDir) > 0 {
+		store, err = accord.OpenSnapshotStore(storeDir)
+
+		if err != nil {
+			fmt.Printf("Error opening snapshot store:%v", err)
+			return
+		}
+	}
+
+	var output interface{}
+
+	//  The runs command only looks at what's been saved, so it doesn't need the services
+	if command == commandRuns {
+		if store == nil {
+			fmt.Printf("Error reading runs:the %v command needs --store", commandRuns)
+			return
+		}
+
+		switch flag.Arg(1) {
+		case runsList:
+			output, err = store.List()
+		case runsShow:
+			if replayDebtID < 0 {
+				output, err = store.ShowRun(runID)
+			} else {
+				output, err = store.ShowDebt(runID, replayDebtID)
+			}
+		case runsPrune:
+			var cutoff time.Time
+
+			if olderThanDays > 0 {
+				cutoff = time.Now().AddDate(0, 0, -olderThanDays)
+			} else if keepRuns <= 0 {
+				fmt.Printf("Error pruning runs:expected --keep or --older-than")
+				return
+			}
+			output, err = store.Prune(keepRuns, cutoff)
+		default:
+			fmt.Printf("Unknown runs command %v; expected %v, %v or %v", flag.Arg(1), runsList, runsShow, runsPrune)
+			return
+		}
+
+		if err != nil {
+			fmt.Printf("Error reading runs:%v", err)
+			return
+		}
+		printJSON(output)
+		return
+	}
+
+	//  diff [FROM [TO]] compares two saved runs, or a saved run (the latest by default) with what the services return now
+	var diffFrom accord.RunSnapshot
+
+	if command == commandDiff {
+		if store == nil {
+			fmt.Printf("Error comparing runs:the %v command needs --store", commandDiff)
+			return
+		}
+
+		from := flag.Arg(1)
+
+		if len(from) == 0 {
+			from = accord.LatestRun
+		}
+
+		diffFrom, err = store.Load(from)
+
+		if err != nil {
+			fmt.Printf("Error comparing runs:%v", err)
+			return
+		}
+
+		if to := flag.Arg(2); len(to) > 0 && to != accord.LiveRun {
+			diffTo, err := store.Load(to)
+
+			if err != nil {
+				fmt.Printf("Error comparing runs:%v", err)
+				return
+			}
+
+			report, err := accord.BuildDiffReport(diffFrom.ID, diffFrom.Debts, diffTo.ID, diffTo.Debts)
+
+			if err != nil {
+				fmt.Printf("Error comparing runs:%v", err)
+				return
+			}
+			printDiffReport(report, format)
+			return
+		}
+	}
+
+	//  Retrieve the debts, plans and payments
+	inputs, err := retrieveInputs()
 
 	if err != nil {
 		fmt.Printf("Error populating debts:%v", err)
 		return
 	}
 
-	debtList = make([]Debt, len(debts))
+	//  ...and work everything out from them
+	portfolio, err := accord.ComputePortfolio(inputs, options)
+
+	if err != nil {
+		fmt.Printf("Error populating debts:%v", err)
+		return
+	}
+
+	//  diff only looks at the live data, so comparing doesn't add a run to the history it compares against.
+	//  The runs command never gets this far
+	if store != nil && command != commandDiff {
+		err = store.Save(portfolio.Snapshot(time.Now(), inputs))
+
+		if err != nil {
+			fmt.Printf("Error saving run:%v", err)
+			return
+		}
+	}
+
+	switch command {
+	case commandRefunds:
+		output = portfolio.Refunds()
+	case commandSettlements:
+		output = portfolio.Settlements()
+	case commandLedger:
+		output = portfolio.Ledger()
+	case commandStatements:
+		output = portfolio.Statements()
+	case commandHistory:
+		output = portfolio.History()
+	case commandReplay:
+		output, err = portfolio.Replay(replayDebtID, replayEvent)
+
+		if err != nil {
+			fmt.Printf("Error replaying debt:%v", err)
+			return
+		}
+	case commandDiff:
+		report, err := accord.BuildDiffReport(diffFrom.ID, diffFrom.Debts, accord.LiveRun, portfolio.DebtList(nil))
+
+		if err != nil {
+			fmt.Printf("Error comparing runs:%v", err)
+			return
+		}
+		printDiffReport(report, format)
+		return
+	case commandTimeline:
+		timelines, err := portfolio.Timelines(replayDebtID)
+
+		if err != nil {
+			fmt.Printf("Error building timeline:%v", err)
+			return
+		}
+
+		if format == formatCSV {
+			err = accord.WriteTimelinesCSV(os.Stdout, timelines)
+
+			if err != nil {
+				fmt.Printf("Error writing timeline:%v", err)
+			}
+			return
+		}
+		output = timelines
+	default:
+		//  Leave out anything the status filter doesn't ask for
+		output = portfolio.DebtList(statuses)
+	}
+
+	printJSON(output)
+
+	return
+}
+
+//  printDiffReport writes a diff report to stdout as JSON, or as text for people
+func printDiffReport(report accord.DiffReport, format string) {
+	if format != formatText {
+		printJSON(report)
+		return
+	}
 
-	idx := 0
+	err := accord.WriteDiffText(os.Stdout, report)
 
-	for _, debt := range debts {
-		debtList[idx] = debt
-		idx++
+	if err != nil {
+		fmt.Printf("Error writing diff:%v", err)
 	}
+}
 
-	bytes, tempError := json.MarshalIndent(debtList, "", "   ")
+//  printJSON writes output to stdout as indented JSON
+func printJSON(output interface{}) {
+	bytes, tempError := json.MarshalIndent(output, "", "   ")
 
 	if tempError != nil {
 		fmt.Printf("Error marshalling output:%v", tempError)
 	} else {
 		fmt.Printf("%v\n", string(bytes))
 	}
-
-	return
 }
 
-//  Make calls to the services to retrieve the related data objects and
-//  return a graph of the data.
+//  Make calls to the services to retrieve the related data objects.
 //  I'm aware of the memory implications of this, but as the
 //  services operations are currently designed (specifically, we get the
 //  entirety of a result-set with each call, rather than being able
@@ -129,8 +390,8 @@ func main() {
 //  of volume in production and generally would be quite gnarly.
 //  2. Cache all our entries locally in memory.
 //  Obviously, we chose option 2
-func populateDebtHierarchy(debts *map[int]Debt) error {
-	var err error = nil
+func retrieveInputs() (accord.Inputs, error) {
+	var rvalue accord.Inputs
 
 	var debtsChannel chan DebtsReturn = nil
 	var paymentPlanChannel chan PaymentPlansReturn = nil
@@ -145,18 +406,15 @@ func populateDebtHierarchy(debts *map[int]Debt) error {
 	go retrievePaymentPlans(paymentPlanChannel, paymentPlanApiServer)
 	go retrievePayments(paymentsChannel, paymentsApiServer)
 
-	var plans map[int]PaymentPlan
-	var payments []Payment
-
 	//  I didn't use a waitgroup here because I need a timeout
 	for timedOut := false; waitCount < 3 && timedOut != true; {
 		select {
 		case debtWrapper := <-debtsChannel:
 			waitCount++
 			if debtWrapper.err == nil {
-				*debts = debtWrapper.debts
+				rvalue.Debts = debtWrapper.debts
 			} else {
-				fmt.Errorf("Error encountered retrieving or parsing Debts:%v", debtWrapper.err)
+				fmt.Printf("Error encountered retrieving or parsing Debts:%v\n", debtWrapper.err)
 			}
 			if waitCount > 2 {
 				break
@@ -164,9 +422,9 @@ func populateDebtHierarchy(debts *map[int]Debt) error {
 		case planWrapper := <-paymentPlanChannel:
 			waitCount++
 			if planWrapper.err == nil {
-				plans = planWrapper.paymentPlans
+				rvalue.PaymentPlans = planWrapper.paymentPlans
 			} else {
-				fmt.Errorf("Error encountered retrieving or parsing Payment Plans:%v", planWrapper.err)
+				fmt.Printf("Error encountered retrieving or parsing Payment Plans:%v\n", planWrapper.err)
 			}
 
 			if waitCount > 2 {
@@ -175,9 +433,9 @@ func populateDebtHierarchy(debts *map[int]Debt) error {
 		case paymentsWrapper := <-paymentsChannel:
 			waitCount++
 			if paymentsWrapper.err == nil {
-				payments = paymentsWrapper.payments
+				rvalue.Payments = paymentsWrapper.payments
 			} else {
-				fmt.Errorf("Error encountered retrieving or parsing Payment Plans:%v", paymentsWrapper.err)
+				fmt.Printf("Error encountered retrieving or parsing Payments:%v\n", paymentsWrapper.err)
 			}
 
 			if waitCount > 2 {
@@ -191,80 +449,11 @@ func populateDebtHierarchy(debts *map[int]Debt) error {
 		}
 	}
 
-	if plans == nil || debts == nil || payments == nil {
-		return fmt.Errorf("There was a problem gathering Debts, Payments, or Payment Plans.")
-	}
-
-	//  Since all this ends up being hierarchical anyway, let's make it a graph
-	err = normalizeData(*debts, plans, payments)
-
-	if err != nil {
-		return fmt.Errorf("Unexpected error encountered flattening data:%v", err)
-	}
-
-	return err
-}
-
-//  normalizeData takes the disparate objects returned by the various web-service calls and place them
-//  into a nice neat hierarchy, matching paymentPlans to debts and putting payments under payment plans
-func normalizeData(debts map[int]Debt, paymentPlans map[int]PaymentPlan, payments []Payment) error {
-	var err error = nil
-	for debtId, debt := range debts {
-
-		//  Does this debt have an associated payment plan?
-		plan, ok := paymentPlans[debtId]
-
-		if ok {
-			debt.paymentPlan = &plan
-
-			//  remove it from the map since we don't need it broken out anymore.
-			//  Besides, we shall do some data integrity checking at the end to
-			//  detect orphans
-			delete(paymentPlans, debtId)
-
-			//  Now attach the payments for that particular payment plan
-			planId := debt.paymentPlan.ID
-
-			//  We will use this slice to build up a list of payments that are relevant to a
-			//  given payment plan
-			var tempPayments []Payment
-
-			//  Iterate through all the payments, matching the payments by plan id
-			//  to their owner plans
-			for _, pmt := range payments {
-				if pmt.PaymentPlanID == planId {
-					tempPayments = append(tempPayments, pmt)
-				}
-			}
-			//  Store those payments in the plan
-			debt.paymentPlan.payments = tempPayments
-
-			//  Generate a payment schedule based on the parameters,
-			//  which would probably be needed by a UI somewhere anyway
-			debt.paymentPlan.generatePaymentSchedule()
-
-			//  Tag the payments that are scheduled
-			debt.paymentPlan.tagScheduledPayments()
-
-			//  Get the next payment date based on the payments that have
-			//  been made
-			if !debt.isDebtPaidOff() {
-				debt.calculateNextPaymentDate(true)
-			}
-
-			debt.InPaymentPlan = debt.isPaymentPlanActive()
-		} // end if ok
-		//  Store the modified debt object back in the collection
-		debts[debtId] = debt
-	} //  end outer debt loop
-
-	//  If we have any plans leftover, that's an error
-	if len(paymentPlans) > 1 {
-		//  in a production system these would show up in an exception report.
-		err = fmt.Errorf("Found orphaned payment plans")
+	if rvalue.Debts == nil || rvalue.PaymentPlans == nil || rvalue.Payments == nil {
+		return rvalue, fmt.Errorf("There was a problem gathering Debts, Payments, or Payment Plans.")
 	}
 
-	return err
+	return rvalue, nil
 }
 
 //  retrievePayments makes the webservice call to retrieve payments from a debt
@@ -324,7 +513,7 @@ func retrievePayments(results chan PaymentsReturn, serverUri string) {
 	}
 
 	//  Pull out the list of debts first as an array
-	var paymentsList []Payment
+	var paymentsList []accord.Payment
 	err = json.Unmarshal(bytes, &paymentsList)
 
 	//  Make sure the json parsed okay
@@ -334,21 +523,8 @@ func retrievePayments(results chan PaymentsReturn, serverUri string) {
 		return
 	}
 
-	for _, pmt := range paymentsList {
-		if len(pmt.Date) > 0 {
-			pmt.date, err = time.Parse(isoDateLayout, pmt.Date)
-
-			if err != nil {
-				rvalue.err = err
-				results <- rvalue
-				return
-			}
-		}
-		rvalue.payments = append(rvalue.payments, pmt)
-
-	}
-	//  Sort the payments by date to make our lives easier later
-	sort.Slice(rvalue.payments, func(i, j int) bool { return rvalue.payments[i].date.Before(rvalue.payments[j].date) })
+	//  Checking and normalizing them is up to ComputePortfolio
+	rvalue.payments = paymentsList
 	results <- rvalue
 }
 
@@ -409,7 +585,7 @@ func retrieveDebts(results chan DebtsReturn, serverUri string) {
 	}
 
 	//  Pull out the list of debts first as an array
-	var debtList []Debt
+	var debtList []accord.Debt
 	err = json.Unmarshal(bytes, &debtList)
 
 	//  Make sure the json parsed okay
@@ -418,13 +594,9 @@ func retrieveDebts(results chan DebtsReturn, serverUri string) {
 		results <- rvalue
 		return
 	}
-	//  Now turn those into a map and return
-	rvalue.debts = make(map[int]Debt)
-
-	for _, debt := range debtList {
-		rvalue.debts[debt.ID] = debt
-	}
 
+	//  Checking and normalizing them is up to ComputePortfolio
+	rvalue.debts = debtList
 	results <- rvalue
 }
 
@@ -485,7 +657,7 @@ func retrievePaymentPlans(results chan PaymentPlansReturn, serverUri string) {
 	}
 
 	//  Pull out the list of payment plans first as an array
-	var paymentPlans []PaymentPlan
+	var paymentPlans []accord.PaymentPlan
 	err = json.Unmarshal(bytes, &paymentPlans)
 
 	//  Make sure the json parsed okay
@@ -495,304 +667,7 @@ func retrievePaymentPlans(results chan PaymentPlansReturn, serverUri string) {
 		return
 	}
 
-	//  Now turn those into a map and return
-	rvalue.paymentPlans = make(map[int]PaymentPlan)
-
-	//  Use the Debt-id as a key since we're going to have to perform lookups based on that
-	for _, plan := range paymentPlans {
-
-		//  While we're at it, parse the dates..
-		if len(plan.StartDate) > 0 {
-			plan.startDate, err = time.Parse(isoDateLayout, plan.StartDate)
-
-			if err != nil {
-				rvalue.err = err
-				results <- rvalue
-				return
-			}
-		}
-
-		rvalue.paymentPlans[plan.DebtID] = plan
-	}
-
+	//  Checking and normalizing them is up to ComputePortfolio
+	rvalue.paymentPlans = paymentPlans
 	results <- rvalue
 }
-
-//  sumTotalPayments adds all payments that have been made to a debt
-func (debt *Debt) sumTotalPayments() (decimal.Decimal, int) {
-	var rvalue decimal.Decimal
-	var paymentCount int
-
-	if debt.paymentPlan != nil {
-
-		plan := debt.paymentPlan
-
-		if plan.payments != nil {
-			for _, payment := range plan.payments {
-				paymentCount++
-				rvalue = rvalue.Add(payment.Amount).Round(2)
-			}
-		}
-	}
-
-	return rvalue, paymentCount
-}
-
-//  isDebtPaidOff checks if a debt is paid or not
-func (debt *Debt) isDebtPaidOff() bool {
-	rc := false
-	if !debt.remainingAmountCalculated {
-		debt.calculateRemainingAmount(true)
-	}
-	//  Check for zero or negative. It's possible they over-paid
-	if debt.RemainingAmount.IsZero() || debt.RemainingAmount.IsNegative() {
-		rc = true
-	}
-	return rc
-}
-
-//  calculateRemainingAmount determines how much money is still left over in the debt
-func (debt *Debt) calculateRemainingAmount(updateObject bool) decimal.Decimal {
-	var rvalue decimal.Decimal
-
-	//  See how much has been paid, if anything
-	amountPaid, _ := debt.sumTotalPayments()
-
-	//  Start by the setting to the debt's amount
-	rvalue = debt.Amount
-
-	//  If there's a payment plan, use the amount_to_pay from there
-	if debt.paymentPlan != nil {
-		if !debt.paymentPlan.AmountToPay.Equal(debt.Amount) {
-			//  Add a check for zero; we don't want to completely wipe out their debt
-			//  ..or do we?
-			if !debt.paymentPlan.AmountToPay.IsZero() {
-				rvalue = debt.paymentPlan.AmountToPay
-			}
-		}
-	}
-
-	//  Now set the remaining amount on the object
-	rvalue = rvalue.Sub(amountPaid).Round(2)
-	if updateObject {
-		debt.remainingAmountCalculated = true
-		debt.RemainingAmount = rvalue
-	}
-	return rvalue
-}
-
-func (debt *Debt) isPaymentPlanActive() bool {
-	rc := false
-
-	if debt.paymentPlan != nil {
-		if !debt.isDebtPaidOff() {
-			rc = true
-		}
-	}
-	return rc
-}
-
-//  calculateNextPayemntDate calculates the next payment date from a startdate and frequency
-func (debt *Debt) calculateNextPaymentDate(updateObject bool) string {
-	var nextPaymentDate string
-
-	//  First make sure a payment plan is active
-	if debt.isPaymentPlanActive() {
-		var nextScheduledDate time.Time
-
-		//  Does this debt have any outstanding payments?
-		paymentCount := len(debt.paymentPlan.payments)
-		if paymentCount > 0 {
-
-			//  Starting with most recent payment made and working backwards,
-			//  Grab the last SCHEDULED payment that was made and then add the payment period
-			for i := paymentCount - 1; i >= 0 && nextScheduledDate.IsZero(); i-- {
-				pmt := &debt.paymentPlan.payments[i]
-
-				//  Did the pmt fall on a scheduled payment date? If not, we need to find one that
-				//  did, as unscheduled payments don't count as scheduled
-				if !pmt.scheduled {
-					continue
-				}
-
-				lastScheduledPaymentDate := pmt.date
-
-				//  This shouldn't be zero
-				if lastScheduledPaymentDate.IsZero() {
-					return nextPaymentDate
-				} else {
-					d, tempErr := paymentFrequencyAsDuration(debt.paymentPlan.InstallmentFrequency)
-
-					if tempErr != nil {
-						return nextPaymentDate
-					}
-
-					//  Add the duration to the schedule
-					nextScheduledDate = lastScheduledPaymentDate.Add(d)
-				}
-			}
-		}
-		if nextScheduledDate.IsZero() {
-			//  If we get here, then none of their payments were made on schedule
-			nextScheduledDate = debt.paymentPlan.startDate
-		}
-		nextPaymentDate = nextScheduledDate.Format(isoDateLayout)
-
-		if updateObject && len(nextPaymentDate) > 0 {
-			debt.NextPaymentDate = &nextPaymentDate
-		}
-
-	}
-
-	return nextPaymentDate
-}
-
-//  paymentFrequencyAsDuration converts a payment frequency to a go time.Duration; used
-//  for adding dates
-func paymentFrequencyAsDuration(freq string) (time.Duration, error) {
-	var rvalue time.Duration
-	var err error = nil
-	var frequency string
-	var dayAddValue int
-
-	//  Normalize our comparison to lowercase; you never know what those Scala services are up to 😁
-	frequency = strings.ToLower(freq)
-
-	switch frequency {
-	case weekly:
-		dayAddValue = 7
-		break
-
-	case biweekly:
-		dayAddValue = 14
-		break
-	default:
-		//  punt if we got something unexpected
-		return rvalue, fmt.Errorf("Received unexpected value of %v in payment frequency", freq)
-	}
-
-	rvalue, err = time.ParseDuration(fmt.Sprintf("%vh", 24*dayAddValue))
-
-	return rvalue, err
-}
-
-//  Not used, but left-in for posterity- I did this before I re-read the spec and saw this important point-
-//  Payments made on days outside the expected payment schedule still go toward paying off the remaining_amount, but do not change/delay the payment schedule.
-func (debt *Debt) lastScheduledDateNotExceedingPaymentDate(date time.Time) (time.Time, error) {
-	var rvalue time.Time
-	var err error = nil
-
-	if debt.isPaymentPlanActive() {
-		var current time.Time
-		var last time.Time
-
-		var frequencyDuration time.Duration
-
-		frequencyDuration, err = paymentFrequencyAsDuration(debt.paymentPlan.InstallmentFrequency)
-
-		current = debt.paymentPlan.startDate
-		last = current
-
-		for current.Before(date) {
-			//  Add days to hit the next payment cycle point
-			current = current.Add(frequencyDuration)
-
-			//  if the current date is after the payment date, break...
-			if current.After(date) || current == date {
-				break
-			}
-			last = current
-		}
-
-		if current == date {
-			rvalue = current
-		} else {
-			rvalue = last
-		}
-	}
-
-	return rvalue, err
-}
-
-//  datesWithinGracePeriodRange was used to determine if payments
-//  that fell within a few days +/- (but not exactly) of scheduled dates
-//  were counted as payment dates. Ended up not using this, but left in for posterity,
-//  because it would almost certainly be needed live
-func datesWithinGracePeriodRange(t1 time.Time, t2 time.Time) bool {
-	rc := false
-
-	d := t1.Sub(t2)
-
-	if math.Abs(float64(d)) <= float64(gracePeriodDuration) {
-		rc = true
-	}
-	return rc
-}
-
-//  tagScheduledPayments marks payments that fall on a schedule with a flag
-func (plan *PaymentPlan) tagScheduledPayments() {
-	for idx, _ := range plan.payments {
-		plan.payments[idx].scheduled = plan.isPaymentDateAScheduledDate(plan.payments[idx].date)
-	}
-}
-
-//  generatePaymentSchedule generates a payment schedule based on a plan's start date and frequency.
-//  any payments made not on this schedule is not recognized as having satisfied the schedule.
-//  In a true production environment this requirement make much sense, which is why I started
-// down the pah of added a gracePeroid, but that
-func (plan *PaymentPlan) generatePaymentSchedule() {
-	var err error = nil
-
-	duration, err := paymentFrequencyAsDuration(plan.InstallmentFrequency)
-
-	if err == nil {
-		runningDate := plan.startDate
-		anticipatedDebtAmount := plan.AmountToPay
-
-		for anticipatedDebtAmount.IsPositive() {
-			if plan.schedule == nil {
-				plan.schedule = make(map[time.Time]decimal.Decimal)
-			}
-			plan.schedule[runningDate] = anticipatedDebtAmount
-			runningDate = runningDate.Add(duration)
-			anticipatedDebtAmount = anticipatedDebtAmount.Sub(plan.InstallmentAmount)
-		}
-	}
-}
-
-//  isPaymentDateAScheduledDate is used to see if a specified date is in the schedule
-func (plan *PaymentPlan) isPaymentDateAScheduledDate(paymentDate time.Time) bool {
-	rc := false
-
-	_, ok := plan.schedule[paymentDate]
-
-	rc = ok
-	return rc
-}
-
-//  dumpPaymentSchedule was used during debugging for diagnosing some edge-cases
-func (plan *PaymentPlan) dumpPaymentSchedule() {
-	fmt.Printf("Payment schedule for plan id:%v, startdate:%v, amount:%v\n", plan.ID, plan.startDate.Format(isoDateLayout), plan.AmountToPay)
-	if len(plan.schedule) > 0 {
-		for k, _ := range plan.schedule {
-			fmt.Printf("%v\n", k.Format(isoDateLayout))
-		}
-	} else {
-		fmt.Println("No Scheduled Payments")
-	}
-	fmt.Println()
-}
-
-//  dumpPayments() was used during debugging for diagnosing some edge-cases and left in for posterity
-func (plan *PaymentPlan) dumpPayments() {
-	fmt.Printf("Payments for plan id:%v, startdate:%v, amount:%v\n", plan.ID, plan.startDate.Format(isoDateLayout), plan.AmountToPay)
-	if len(plan.payments) > 0 {
-		for _, pmt := range plan.payments {
-			fmt.Printf("Payment Date:%v   Amount:%v  Scheduled:%v\n", pmt.date.Format(isoDateLayout), pmt.Amount, pmt.scheduled)
-		}
-	} else {
-		fmt.Println("No payments ")
-	}
-
-	fmt.Println()
-}